@@ -0,0 +1,237 @@
+package gofpdi
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Annotation flags (/F)
+const (
+	AnnotationFlagHidden = 1 << 1
+	AnnotationFlagPrint  = 1 << 2
+	AnnotationFlagNoView = 1 << 5
+)
+
+// ImportOption configures optional behavior of PdfWriter.ImportPage
+type ImportOption func(*importOptions)
+
+type importOptions struct {
+	annotations bool
+}
+
+// WithAnnotations merges the normal appearances of the visible, printable annotations of a page (form fields,
+// stamps, highlights, the visible part of signatures, ...) into the imported template, at the position of each
+// annotation.  Annotations are left out if they are hidden, not shown on screen (NoView) or not printed.
+func WithAnnotations() ImportOption {
+	return func(o *importOptions) {
+		o.annotations = true
+	}
+}
+
+// Build the content that paints the normal appearances of the visible, printable annotations of a page, and the
+// Form XObjects it uses, keyed by a resource name that is not used by resources yet
+func (this *PdfReader) getAnnotationAppearances(pageno int, resources *PdfValue) (string, map[string]*PdfValue, error) {
+	xobjects := make(map[string]*PdfValue, 0)
+
+	page, err := this.getPage(pageno)
+	if err != nil {
+		return "", nil, err
+	}
+
+	annotsSpec := page.Key("/Annots")
+	if annotsSpec == nil {
+		return "", xobjects, nil
+	}
+
+	annots, err := this.Resolve(annotsSpec)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Failed to resolve annotations")
+	}
+
+	// Names of the XObjects of the page, which must not be reused
+	used := make(map[string]bool, 0)
+	if existing, err := this.Resolve(resources.Key("/XObject")); err == nil {
+		for _, name := range existing.Keys() {
+			used[name] = true
+		}
+	}
+
+	var content strings.Builder
+
+	array, _ := annots.AsArray()
+	for _, annotSpec := range array {
+		annot, err := this.Resolve(annotSpec)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "Failed to resolve annotation")
+		}
+
+		flags, _ := annot.Key("/F").AsInt()
+		if flags&(AnnotationFlagHidden|AnnotationFlagNoView) != 0 || flags&AnnotationFlagPrint == 0 {
+			continue
+		}
+
+		ref, err := this.annotationAppearance(annot)
+		if err != nil {
+			return "", nil, err
+		}
+		if ref == nil {
+			continue
+		}
+
+		appearance, err := this.Resolve(ref)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "Failed to resolve annotation appearance")
+		}
+
+		matrix, ok := annotationMatrix(annot, appearance)
+		if !ok {
+			continue
+		}
+
+		name := ""
+		for i := len(xobjects) + 1; name == "" || used[name]; i++ {
+			name = fmt.Sprintf("/GOFPDIANNOT%d", i)
+		}
+		used[name] = true
+		xobjects[name] = ref
+
+		content.WriteString(fmt.Sprintf("q %s %s %s %s %s %s cm %s Do Q\n", formatNumber(matrix[0]), formatNumber(matrix[1]),
+			formatNumber(matrix[2]), formatNumber(matrix[3]), formatNumber(matrix[4]), formatNumber(matrix[5]), name))
+	}
+
+	return content.String(), xobjects, nil
+}
+
+// Get a reference to the normal appearance stream of an annotation, selecting the appearance state (/AS) if
+// there are several.  Returns nil if the annotation has no appearance.
+func (this *PdfReader) annotationAppearance(annot *PdfValue) (*PdfValue, error) {
+	apSpec := annot.Key("/AP")
+	if apSpec == nil {
+		return nil, nil
+	}
+
+	ap, err := this.Resolve(apSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve appearance dictionary")
+	}
+
+	normalSpec := ap.Key("/N")
+	if normalSpec == nil {
+		return nil, nil
+	}
+
+	normal, err := this.Resolve(normalSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve normal appearance")
+	}
+	if normal.IsStream() {
+		return normalSpec, nil
+	}
+
+	// A dictionary of appearance states, direct (as for most check boxes and radio buttons) or indirect
+	state, ok := annot.Key("/AS").AsName()
+	if !ok {
+		return nil, nil
+	}
+	if ref := normal.Key(state); ref.IsRef() {
+		return ref, nil
+	}
+
+	return nil, nil
+}
+
+// Compute the matrix that maps the bounding box of an appearance stream, transformed by its /Matrix, onto the
+// rectangle of the annotation (PDF 32000-1:2008, 12.5.5)
+func annotationMatrix(annot, appearance *PdfValue) ([6]float64, bool) {
+	var result [6]float64
+
+	rect, ok := numberArray(annot.Key("/Rect"), 4)
+	if !ok {
+		return result, false
+	}
+	bbox, ok := numberArray(appearance.Key("/BBox"), 4)
+	if !ok {
+		return result, false
+	}
+	m, ok := numberArray(appearance.Key("/Matrix"), 6)
+	if !ok {
+		m = []float64{1, 0, 0, 1, 0, 0}
+	}
+
+	// Transformed bounding box
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{bbox[0], bbox[1]}, {bbox[2], bbox[1]}, {bbox[0], bbox[3]}, {bbox[2], bbox[3]}} {
+		x := m[0]*corner[0] + m[2]*corner[1] + m[4]
+		y := m[1]*corner[0] + m[3]*corner[1] + m[5]
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+
+	if maxX-minX <= 0 || maxY-minY <= 0 {
+		return result, false
+	}
+
+	llx, lly := math.Min(rect[0], rect[2]), math.Min(rect[1], rect[3])
+	urx, ury := math.Max(rect[0], rect[2]), math.Max(rect[1], rect[3])
+
+	sx := (urx - llx) / (maxX - minX)
+	sy := (ury - lly) / (maxY - minY)
+
+	return [6]float64{sx, 0, 0, sy, llx - minX*sx, lly - minY*sy}, true
+}
+
+// Get the numbers of an array with n elements
+func numberArray(value *PdfValue, n int) ([]float64, bool) {
+	array, ok := value.AsArray()
+	if !ok || len(array) != n {
+		return nil, false
+	}
+
+	result := make([]float64, n)
+	for i, v := range array {
+		if result[i], ok = v.AsFloat(); !ok {
+			return nil, false
+		}
+	}
+
+	return result, true
+}
+
+// Add the appearances of the annotations of a page to its content and resources.  The resources are copied, as
+// they are shared with the reader.
+func (this *PdfReader) addAnnotationAppearances(pageno int, content string, resources *PdfValue) (string, *PdfValue, error) {
+	annotContent, xobjects, err := this.getAnnotationAppearances(pageno, resources)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Failed to get annotation appearances")
+	}
+	if len(xobjects) == 0 {
+		return content, resources, nil
+	}
+
+	resources = copyPdfValue(resources)
+	if resources.Dictionary == nil {
+		resources = &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: make(map[string]*PdfValue, 0)}
+	}
+
+	existing := &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: make(map[string]*PdfValue, 0)}
+	if xobjectSpec := resources.Dictionary["/XObject"]; xobjectSpec != nil {
+		xobject, err := this.Resolve(xobjectSpec)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "Failed to resolve XObject resources")
+		}
+		if _, ok := xobject.AsDict(); ok {
+			existing = copyPdfValue(xobject.direct())
+		}
+	}
+	for name, ref := range xobjects {
+		existing.Dictionary[name] = ref
+	}
+	resources.Dictionary["/XObject"] = existing
+
+	// The page content may leave the graphics state changed
+	return "q\n" + content + "\nQ\n" + annotContent, resources, nil
+}
@@ -0,0 +1,130 @@
+package gofpdi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnnotationAppearances(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /XObject << /GOFPDIANNOT1 10 0 R >> >> /Annots [4 0 R 5 0 R 6 0 R 7 0 R] >>",
+		"<< /Subtype /Stamp /F 4 /Rect [100 200 300 250] /AP << /N 8 0 R >> >>",
+		"<< /Subtype /Stamp /F 6 /Rect [0 0 100 25] /AP << /N 8 0 R >> >>",
+		"<< /Subtype /Stamp /F 0 /Rect [0 0 100 25] /AP << /N 8 0 R >> >>",
+		"<< /Subtype /Widget /F 4 /Rect [10 10 20 20] /AS /Yes /AP << /N << /Yes 9 0 R /Off 10 0 R >> >> >>",
+		"<< /Type /XObject /Subtype /Form /BBox [0 0 100 25] /Length 8 >>\nstream\n0 0 m S\n\nendstream",
+		"<< /Type /XObject /Subtype /Form /BBox [0 0 10 10] /Length 8 >>\nstream\n1 1 m S\n\nendstream",
+		"<< /Type /XObject /Subtype /Form /BBox [0 0 10 10] /Length 8 >>\nstream\n2 2 m S\n\nendstream",
+	)
+
+	resources, err := reader.getPageResources(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, result, err := reader.addAnnotationAppearances(1, "BT ET", resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hidden and unprinted annotations are skipped, and the name already used by the page is not reused
+	want := "q\nBT ET\nQ\n" +
+		"q 2 0 0 2 100 200 cm /GOFPDIANNOT2 Do Q\n" +
+		"q 1 0 0 1 10 10 cm /GOFPDIANNOT3 Do Q\n"
+	if content != want {
+		t.Errorf("content %q, want %q", content, want)
+	}
+
+	xobjects, err := reader.Resolve(result.Key("/XObject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, id := range map[string]int{"/GOFPDIANNOT1": 10, "/GOFPDIANNOT2": 8, "/GOFPDIANNOT3": 9} {
+		if ref := xobjects.Key(name); !ref.IsRef() || ref.Id != id {
+			t.Errorf("XObject %s is %v, want object %d", name, ref, id)
+		}
+	}
+
+	// The resources of the reader are left alone
+	if existing, _ := reader.Resolve(resources.Key("/XObject")); len(existing.Keys()) != 1 {
+		t.Errorf("page resources changed: %v", existing.Keys())
+	}
+}
+
+func TestAnnotationWithoutAppearances(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Annots [4 0 R] >>",
+		"<< /Subtype /Link /F 4 /Rect [0 0 100 25] >>",
+	)
+
+	content, resources, err := reader.addAnnotationAppearances(1, "BT ET", &PdfValue{Type: PDF_TYPE_NULL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "BT ET" || !resources.IsNull() {
+		t.Errorf("content %q and resources %v changed", content, resources)
+	}
+}
+
+func TestAnnotationMatrix(t *testing.T) {
+	parse := func(s string) *PdfValue {
+		reader := readTestPDF(t, "<< /Type /Catalog /Pages 3 0 R >>", s, "<< /Type /Pages /Kids [] /Count 0 >>")
+		value, err := reader.Object(2, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	tests := []struct {
+		annot, appearance string
+		want              [6]float64
+		ok                bool
+	}{
+		{"<< /Rect [100 200 300 250] >>", "<< /BBox [0 0 100 25] >>", [6]float64{2, 0, 0, 2, 100, 200}, true},
+		// Corners in any order, and an offset bounding box
+		{"<< /Rect [300 250 100 200] >>", "<< /BBox [10 10 110 35] >>", [6]float64{2, 0, 0, 2, 80, 180}, true},
+		// A rotated appearance, as generated for widgets with /MK /R 90
+		{"<< /Rect [100 300 120 500] >>", "<< /BBox [0 0 200 20] /Matrix [0 1 -1 0 0 0] >>", [6]float64{1, 0, 0, 1, 120, 300}, true},
+		{"<< /Rect [0 0 10 10] >>", "<< /BBox [0 0 0 10] >>", [6]float64{}, false},
+		{"<< /Rect [0 0 10] >>", "<< /BBox [0 0 10 10] >>", [6]float64{}, false},
+		{"<< /Rect [0 0 10 10] >>", "<< /BBox [0 0 (x) 10] >>", [6]float64{}, false},
+	}
+
+	for i, test := range tests {
+		got, ok := annotationMatrix(parse(test.annot), parse(test.appearance))
+		if ok != test.ok || got != test.want {
+			t.Errorf("test %d: annotationMatrix = %v, %v, want %v, %v", i, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestImportPageWithAnnotations(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Annots [5 0 R] >>",
+		"<< /Length 5 >>\nstream\nBT ET\nendstream",
+		"<< /Subtype /Stamp /F 4 /Rect [100 200 300 250] /AP << /N 6 0 R >> >>",
+		"<< /Type /XObject /Subtype /Form /BBox [0 0 100 25] /Length 8 >>\nstream\n0 0 m S\n\nendstream",
+	)
+
+	for _, opts := range [][]ImportOption{nil, {WithAnnotations()}} {
+		writer, err := NewPdfWriter("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.ImportPage(reader, 1, "/MediaBox", opts...); err != nil {
+			t.Fatal(err)
+		}
+
+		content := writer.tpls[0].Buffer
+		if got := strings.Contains(content, "/GOFPDIANNOT1 Do"); got != (len(opts) > 0) {
+			t.Errorf("options %d: annotation appearances painted: %v", len(opts), got)
+		}
+	}
+}
@@ -0,0 +1,663 @@
+package gofpdi
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Widths of the Helvetica glyphs for the characters 32 to 126, in thousandths of the font size
+var helveticaWidths = []int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// Characters of WinAnsiEncoding in the range 0x80 to 0x9f.  The other characters are those of ISO Latin-1.
+var winAnsiEncoding = map[rune]byte{
+	0x20ac: 0x80, 0x201a: 0x82, 0x0192: 0x83, 0x201e: 0x84, 0x2026: 0x85, 0x2020: 0x86, 0x2021: 0x87, 0x02c6: 0x88,
+	0x2030: 0x89, 0x0160: 0x8a, 0x2039: 0x8b, 0x0152: 0x8c, 0x017d: 0x8e, 0x2018: 0x91, 0x2019: 0x92, 0x201c: 0x93,
+	0x201d: 0x94, 0x2022: 0x95, 0x2013: 0x96, 0x2014: 0x97, 0x02dc: 0x98, 0x2122: 0x99, 0x0161: 0x9a, 0x203a: 0x9b,
+	0x0153: 0x9c, 0x017e: 0x9e, 0x0178: 0x9f,
+}
+
+// Metrics of Helvetica used to place text, as fractions of the font size
+const (
+	textAscent  = 0.718
+	textDescent = 0.207
+	textLeading = 1.15
+)
+
+// Smallest font size used when text is shrunk to fit a field
+const minAutoFontSize = 4
+
+// Encode text in WinAnsiEncoding, keeping line breaks.  Characters that cannot be encoded are replaced with a
+// question mark, and ok is false if there were any.
+func encodeWinAnsi(s string) (result []byte, ok bool) {
+	ok = true
+	for _, r := range s {
+		if b, found := winAnsiEncoding[r]; found {
+			result = append(result, b)
+		} else if r >= 32 && r < 127 || r >= 0xa0 && r <= 0xff {
+			result = append(result, byte(r))
+		} else if r == '\n' || r == '\r' {
+			result = append(result, byte(r))
+		} else if r == '\t' {
+			result = append(result, ' ')
+		} else {
+			result = append(result, '?')
+			ok = false
+		}
+	}
+	return result, ok
+}
+
+// Font used to lay out the text of an appearance stream
+type appearanceFont struct {
+	ref          *PdfValue // font dictionary
+	firstChar    int
+	widths       []float64
+	defaultWidth float64
+}
+
+// Width of encoded text, in thousandths of the font size
+func (this *appearanceFont) width(text []byte) float64 {
+	width := 0.0
+	for _, c := range text {
+		i := int(c) - this.firstChar
+		if i >= 0 && i < len(this.widths) && this.widths[i] > 0 {
+			width += this.widths[i]
+		} else {
+			width += this.defaultWidth
+		}
+	}
+	return width
+}
+
+// Default appearance of variable text (/DA), e.g. "/Helv 12 Tf 0 g"
+type defaultAppearance struct {
+	font  string  // font resource name
+	size  float64 // font size, 0 for auto size
+	color string  // color operator with its operands, e.g. "0 g"
+}
+
+// Parse a default appearance string.  Operators other than Tf and the fill color operators are ignored.
+func parseDefaultAppearance(da string) defaultAppearance {
+	result := defaultAppearance{font: "/Helv", color: "0 g"}
+
+	operands := make([]string, 0)
+	for _, token := range strings.Fields(da) {
+		switch token {
+		case "Tf":
+			if len(operands) >= 2 {
+				result.font = operands[len(operands)-2]
+				result.size, _ = strconv.ParseFloat(operands[len(operands)-1], 64)
+			}
+		case "g", "rg", "k":
+			result.color = strings.Join(append(operands, token), " ")
+		default:
+			operands = append(operands, token)
+			continue
+		}
+		operands = operands[:0]
+	}
+
+	return result
+}
+
+// Get the color operator for a color array (/MK /BG or /BC) with 1, 3 or 4 components.  Returns an empty string
+// for an empty (transparent) color.
+func colorOperator(color *PdfValue, stroke bool) string {
+	array, _ := color.AsArray()
+
+	var op string
+	switch len(array) {
+	case 1:
+		op = "g"
+	case 3:
+		op = "rg"
+	case 4:
+		op = "k"
+	default:
+		return ""
+	}
+	if stroke {
+		op = strings.ToUpper(op)
+	}
+
+	components := make([]string, 0, len(array))
+	for _, c := range array {
+		f, _ := c.AsFloat()
+		components = append(components, formatNumber(f))
+	}
+
+	return strings.Join(components, " ") + " " + op
+}
+
+// Format a number for a content stream
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
+
+// Look up an inheritable attribute (e.g. /DA or /Q) of a widget in the widget, its field and the field's
+// ancestors, and the interactive form dictionary.  Returns nil if it is not defined.
+func (this *FormFiller) fieldAttribute(widget *PdfValue, key string) (*PdfValue, error) {
+	obj := widget
+	for i := 0; i < maxReferenceChain; i++ {
+		if value := obj.Key(key); value != nil {
+			return this.reader.Resolve(value)
+		}
+
+		parent := obj.Key("/Parent")
+		if !parent.IsRef() {
+			break
+		}
+
+		var err error
+		obj, err = this.update.object(parent.Id, parent.Gen)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read parent field")
+		}
+	}
+
+	if value := this.acroForm.Key(key); value != nil {
+		return this.reader.Resolve(value)
+	}
+
+	return nil, nil
+}
+
+// Get the font of an appearance stream from the default resources of the form (/DR).  Composite fonts, fonts with
+// an encoding other than WinAnsiEncoding and fonts that are not defined are replaced by Helvetica.
+func (this *FormFiller) appearanceFont(name string) (*appearanceFont, error) {
+	if font, ok := this.fonts[name]; ok {
+		return font, nil
+	}
+
+	var font *appearanceFont
+
+	if dr, err := this.reader.Resolve(this.acroForm.Key("/DR")); err == nil {
+		if fonts, err := this.reader.Resolve(dr.Key("/Font")); err == nil {
+			if ref := fonts.Key(name); ref != nil {
+				dict, err := this.reader.Resolve(ref)
+				if err != nil {
+					return nil, errors.Wrap(err, "Failed to resolve font "+name)
+				}
+
+				if subtype, _ := dict.Key("/Subtype").AsName(); subtype != "/Type0" && this.winAnsiFont(dict) {
+					font, err = this.simpleFont(ref, dict)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	if font == nil {
+		font = standardFontMetrics("/Helvetica")
+		font.ref = this.standardFontRef("/Helvetica")
+	}
+
+	this.fonts[name] = font
+
+	return font, nil
+}
+
+// Check whether a font uses WinAnsiEncoding, which appearance text is encoded with
+func (this *FormFiller) winAnsiFont(dict *PdfValue) bool {
+	encoding, err := this.reader.Resolve(dict.Key("/Encoding"))
+	if err != nil {
+		return false
+	}
+	if name, ok := encoding.AsName(); ok {
+		return name == "/WinAnsiEncoding"
+	}
+
+	base, _ := encoding.Key("/BaseEncoding").AsName()
+	return base == "/WinAnsiEncoding" && encoding.Key("/Differences") == nil
+}
+
+// Get the metrics of a simple font from its /Widths, or from the standard font it names
+func (this *FormFiller) simpleFont(ref, dict *PdfValue) (*appearanceFont, error) {
+	widthsSpec := dict.Key("/Widths")
+	if widthsSpec == nil {
+		base, _ := dict.Key("/BaseFont").AsName()
+		font := standardFontMetrics(base)
+		font.ref = ref
+		return font, nil
+	}
+
+	widths, err := this.reader.Resolve(widthsSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve font widths")
+	}
+
+	font := &appearanceFont{ref: ref, defaultWidth: 500}
+	font.firstChar, _ = dict.Key("/FirstChar").AsInt()
+
+	array, _ := widths.AsArray()
+	for _, w := range array {
+		w, _ = this.reader.Resolve(w)
+		width, _ := w.AsFloat()
+		font.widths = append(font.widths, width)
+	}
+
+	if descriptor, err := this.reader.Resolve(dict.Key("/FontDescriptor")); err == nil {
+		if missing, ok := descriptor.Key("/MissingWidth").AsFloat(); ok && missing > 0 {
+			font.defaultWidth = missing
+		}
+	}
+
+	return font, nil
+}
+
+// Get the metrics of a standard font.  Fonts other than Courier are measured as Helvetica.
+func standardFontMetrics(base string) *appearanceFont {
+	if strings.Contains(base, "Courier") {
+		return &appearanceFont{defaultWidth: 600}
+	}
+
+	font := &appearanceFont{firstChar: 32, defaultWidth: 556}
+	for _, w := range helveticaWidths {
+		font.widths = append(font.widths, float64(w))
+	}
+
+	return font
+}
+
+// Add the font dictionary of a standard font to the update (once)
+func (this *FormFiller) standardFontRef(base string) *PdfValue {
+	if ref, ok := this.standardFonts[base]; ok {
+		return ref
+	}
+
+	dict := map[string]*PdfValue{
+		"/Type":     {Type: PDF_TYPE_TOKEN, Token: "/Font"},
+		"/Subtype":  {Type: PDF_TYPE_TOKEN, Token: "/Type1"},
+		"/BaseFont": {Type: PDF_TYPE_TOKEN, Token: base},
+	}
+	if base != "/ZapfDingbats" {
+		dict["/Encoding"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: "/WinAnsiEncoding"}
+	}
+
+	ref := this.update.AddObject(&PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: dict})
+	this.standardFonts[base] = ref
+
+	return ref
+}
+
+// Generate the normal appearance of the widgets of a text field or combo box, showing text.  If the text contains
+// characters that cannot be encoded, viewers are asked to regenerate the appearance.
+func (this *FormFiller) setTextAppearance(field *FormField, text string) error {
+	if field.Flags&FieldFlagPassword != 0 {
+		text = strings.Repeat("*", len([]rune(text)))
+	}
+
+	encoded, ok := encodeWinAnsi(text)
+	if !ok {
+		if err := this.setNeedAppearances(); err != nil {
+			return err
+		}
+	}
+
+	multiline := field.Type == FormFieldText && field.Flags&FieldFlagMultiline != 0
+	if !multiline {
+		for i, c := range encoded {
+			if c == '\n' || c == '\r' {
+				encoded[i] = ' '
+			}
+		}
+	}
+	comb := field.Type == FormFieldText && field.Flags&FieldFlagComb != 0 && field.MaxLen > 0 &&
+		field.Flags&(FieldFlagMultiline|FieldFlagPassword|FieldFlagFileSelect) == 0
+
+	for _, widget := range field.Widgets {
+		if widget.id == 0 {
+			continue
+		}
+
+		obj, err := this.update.EditObject(widget.id, widget.gen)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read widget annotation")
+		}
+		dict, ok := obj.AsDict()
+		if !ok {
+			continue
+		}
+
+		daValue, err := this.fieldAttribute(obj, "/DA")
+		if err != nil {
+			return err
+		}
+		daBytes, _ := daValue.AsBytes()
+		da := parseDefaultAppearance(string(daBytes))
+
+		qValue, err := this.fieldAttribute(obj, "/Q")
+		if err != nil {
+			return err
+		}
+		q, _ := qValue.AsInt()
+
+		font, err := this.appearanceFont(da.font)
+		if err != nil {
+			return err
+		}
+
+		width := widget.Rect[2] - widget.Rect[0]
+		height := widget.Rect[3] - widget.Rect[1]
+
+		// Text of rotated widgets runs along the rotated sides
+		rotation := this.widgetRotation(obj)
+		if rotation == 90 || rotation == 270 {
+			width, height = height, width
+		}
+
+		var content bytes.Buffer
+		border := this.writeWidgetBackground(&content, obj, width, height)
+
+		// Text is clipped to the area inside the border and laid out with some padding
+		padding := border + 2
+		inner := width - 2*padding
+
+		content.WriteString("/Tx BMC\nq\n")
+		content.WriteString(fmt.Sprintf("%s %s %s %s re W n\n", formatNumber(border), formatNumber(border),
+			formatNumber(width-2*border), formatNumber(height-2*border)))
+		content.WriteString("BT\n")
+
+		var lines [][]byte
+		size := da.size
+
+		switch {
+		case multiline:
+			if size <= 0 {
+				// Shrink the text from 12 points until all lines fit
+				size = 12
+				for size > minAutoFontSize && float64(len(wrapText(encoded, font, size, inner)))*size*textLeading > height-2*padding {
+					size--
+				}
+			}
+			lines = wrapText(encoded, font, size, inner)
+		case comb:
+			if size <= 0 {
+				size = math.Min((height-2*border)/textLeading, 12)
+			}
+			lines = [][]byte{encoded}
+		default:
+			if size <= 0 {
+				size = (height - 2*border) / textLeading
+				if w := font.width(encoded) / 1000 * size; w > inner && w > 0 {
+					size *= inner / w
+				}
+				size = math.Max(size, minAutoFontSize)
+			}
+			lines = [][]byte{encoded}
+		}
+
+		content.WriteString(fmt.Sprintf("%s %s Tf %s\n", da.font, formatNumber(size), da.color))
+
+		x0, y0 := 0.0, 0.0
+		moveTo := func(x, y float64) {
+			content.WriteString(fmt.Sprintf("%s %s Td\n", formatNumber(x-x0), formatNumber(y-y0)))
+			x0, y0 = x, y
+		}
+
+		if comb {
+			// Each character is centered in one of MaxLen cells
+			cell := (width - 2*border) / float64(field.MaxLen)
+			y := (height - (textAscent-textDescent)*size) / 2
+			for i, c := range encoded {
+				if i >= field.MaxLen {
+					break
+				}
+				moveTo(border+cell*float64(i)+(cell-font.width([]byte{c})/1000*size)/2, y)
+				content.WriteString("(" + escapeLiteralString([]byte{c}) + ") Tj\n")
+			}
+		} else {
+			y := (height - (textAscent-textDescent)*size) / 2
+			if multiline {
+				y = height - padding - textAscent*size
+			}
+			for _, line := range lines {
+				x := padding
+				switch q {
+				case 1:
+					x = (width - font.width(line)/1000*size) / 2
+				case 2:
+					x = width - padding - font.width(line)/1000*size
+				}
+				moveTo(x, y)
+				content.WriteString("(" + escapeLiteralString(line) + ") Tj\n")
+				y -= size * textLeading
+			}
+		}
+
+		content.WriteString("ET\nQ\nEMC\n")
+
+		ref, err := this.addAppearanceStream(content.Bytes(), width, height, rotation, da.font, font.ref)
+		if err != nil {
+			return err
+		}
+
+		// Down and rollover appearances would still show the previous value
+		dict["/AP"] = &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{"/N": ref}}
+	}
+
+	return nil
+}
+
+// Split text into lines at line breaks and between words, so that each line fits into width
+func wrapText(text []byte, font *appearanceFont, size, width float64) [][]byte {
+	lines := make([][]byte, 0)
+
+	text = bytes.Replace(text, []byte("\r\n"), []byte("\n"), -1)
+	text = bytes.Replace(text, []byte("\r"), []byte("\n"), -1)
+	for _, paragraph := range bytes.Split(text, []byte("\n")) {
+		var line []byte
+		for i, word := range bytes.Split(paragraph, []byte(" ")) {
+			if i > 0 {
+				candidate := append(append(append([]byte{}, line...), ' '), word...)
+				if font.width(candidate)/1000*size <= width {
+					line = candidate
+					continue
+				}
+				lines = append(lines, line)
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// Paint the background and border of a widget from its appearance characteristics (/MK) and border style (/BS).
+// Returns the border width.
+func (this *FormFiller) writeWidgetBackground(content *bytes.Buffer, widget *PdfValue, width, height float64) float64 {
+	mk, err := this.reader.Resolve(widget.Key("/MK"))
+	if err != nil {
+		return 0
+	}
+
+	if bg := colorOperator(mk.Key("/BG"), false); bg != "" {
+		content.WriteString(fmt.Sprintf("%s\n0 0 %s %s re f\n", bg, formatNumber(width), formatNumber(height)))
+	}
+
+	bc := colorOperator(mk.Key("/BC"), true)
+	if bc == "" {
+		return 0
+	}
+
+	border := 1.0
+	if bs, err := this.reader.Resolve(widget.Key("/BS")); err == nil {
+		if w, ok := bs.Key("/W").AsFloat(); ok {
+			border = w
+		}
+	}
+	if border <= 0 {
+		return 0
+	}
+
+	content.WriteString(fmt.Sprintf("%s\n%s w\n%s %s %s %s re S\n", bc, formatNumber(border), formatNumber(border/2),
+		formatNumber(border/2), formatNumber(width-border), formatNumber(height-border)))
+
+	return border
+}
+
+// Characters of ZapfDingbats used to mark checkboxes and radio buttons, with their widths
+var zapfDingbatsWidths = map[byte]float64{'4': 760, 'l': 791, '8': 754, 'u': 759, 'n': 748, 'H': 816}
+
+// Add appearances for the on and off states of a checkbox or radio button widget that has none
+func (this *FormFiller) setButtonAppearance(field *FormField, widget *PdfValue, state string) error {
+	dict, ok := widget.AsDict()
+	if !ok {
+		return nil
+	}
+
+	rect, _ := this.reader.Resolve(widget.Key("/Rect"))
+	coords, _ := rect.AsArray()
+	if len(coords) != 4 {
+		return nil
+	}
+	var r [4]float64
+	for i := 0; i < 4; i++ {
+		r[i], _ = coords[i].AsFloat()
+	}
+	width := math.Abs(r[2] - r[0])
+	height := math.Abs(r[3] - r[1])
+
+	daValue, err := this.fieldAttribute(widget, "/DA")
+	if err != nil {
+		return err
+	}
+	daBytes, _ := daValue.AsBytes()
+	da := parseDefaultAppearance(string(daBytes))
+
+	// The caption (/MK /CA) is the ZapfDingbats character shown in the on state
+	c := byte('4')
+	if field.Type == FormFieldRadio {
+		c = 'l'
+	}
+	if mk, err := this.reader.Resolve(widget.Key("/MK")); err == nil {
+		if ca, ok := mk.Key("/CA").AsBytes(); ok && len(ca) > 0 {
+			c = ca[0]
+		}
+	}
+	charWidth, ok := zapfDingbatsWidths[c]
+	if !ok {
+		charWidth = 800
+	}
+
+	var off bytes.Buffer
+	border := this.writeWidgetBackground(&off, widget, width, height)
+
+	size := da.size
+	if size <= 0 {
+		size = (math.Min(width, height) - 2*border) * 0.8
+	}
+
+	on := bytes.NewBuffer(append([]byte{}, off.Bytes()...))
+	on.WriteString(fmt.Sprintf("q\nBT\n/ZaDb %s Tf %s\n%s %s Td\n(%s) Tj\nET\nQ\n", formatNumber(size), da.color,
+		formatNumber((width-charWidth/1000*size)/2), formatNumber((height-0.7*size)/2), escapeLiteralString([]byte{c})))
+
+	font := this.zapfDingbats()
+	onRef, err := this.addAppearanceStream(on.Bytes(), width, height, 0, "/ZaDb", font)
+	if err != nil {
+		return err
+	}
+	offRef, err := this.addAppearanceStream(off.Bytes(), width, height, 0, "", nil)
+	if err != nil {
+		return err
+	}
+
+	dict["/AP"] = &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+		"/N": {Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+			"/" + state: onRef,
+			"/Off":      offRef,
+		}},
+	}}
+
+	return nil
+}
+
+// Get the ZapfDingbats font from the default resources of the form, or add it
+func (this *FormFiller) zapfDingbats() *PdfValue {
+	if dr, err := this.reader.Resolve(this.acroForm.Key("/DR")); err == nil {
+		if fonts, err := this.reader.Resolve(dr.Key("/Font")); err == nil {
+			if ref := fonts.Key("/ZaDb"); ref != nil {
+				return ref
+			}
+		}
+	}
+
+	return this.standardFontRef("/ZapfDingbats")
+}
+
+// Get the rotation of a widget (/MK /R) in degrees: 0, 90, 180 or 270
+func (this *FormFiller) widgetRotation(widget *PdfValue) int {
+	mk, err := this.reader.Resolve(widget.Key("/MK"))
+	if err != nil {
+		return 0
+	}
+	r, _ := mk.Key("/R").AsInt()
+	r = (r%360 + 360) % 360
+	if r%90 != 0 {
+		return 0
+	}
+	return r
+}
+
+// Add a Form XObject holding an appearance stream and return a reference to it.  The appearance is drawn in a box of
+// width by height, which is rotated counterclockwise by rotation degrees onto the widget.
+func (this *FormFiller) addAppearanceStream(content []byte, width, height float64, rotation int, fontName string, font *PdfValue) (*PdfValue, error) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write(content); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress appearance stream")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress appearance stream")
+	}
+
+	resources := &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: make(map[string]*PdfValue, 0)}
+	if font != nil {
+		resources.Dictionary["/Font"] = &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{fontName: font}}
+	}
+
+	dict := &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+		"/Type":    {Type: PDF_TYPE_TOKEN, Token: "/XObject"},
+		"/Subtype": {Type: PDF_TYPE_TOKEN, Token: "/Form"},
+		"/BBox": {Type: PDF_TYPE_ARRAY, Array: []*PdfValue{
+			{Type: PDF_TYPE_NUMERIC, Int: 0},
+			{Type: PDF_TYPE_NUMERIC, Int: 0},
+			{Type: PDF_TYPE_REAL, Real: width},
+			{Type: PDF_TYPE_REAL, Real: height},
+		}},
+		"/Resources": resources,
+		"/Filter":    {Type: PDF_TYPE_TOKEN, Token: "/FlateDecode"},
+	}}
+
+	// The annotation's rectangle is fitted to the rotated bounding box, so the matrix needs no translation
+	if rotation != 0 {
+		cos := []int{1, 0, -1, 0}[rotation/90]
+		sin := []int{0, 1, 0, -1}[rotation/90]
+		dict.Dictionary["/Matrix"] = &PdfValue{Type: PDF_TYPE_ARRAY, Array: []*PdfValue{
+			{Type: PDF_TYPE_NUMERIC, Int: cos},
+			{Type: PDF_TYPE_NUMERIC, Int: sin},
+			{Type: PDF_TYPE_NUMERIC, Int: -sin},
+			{Type: PDF_TYPE_NUMERIC, Int: cos},
+			{Type: PDF_TYPE_NUMERIC, Int: 0},
+			{Type: PDF_TYPE_NUMERIC, Int: 0},
+		}}
+	}
+
+	return this.update.AddObject(&PdfValue{Type: PDF_TYPE_STREAM, Value: dict, Stream: &PdfValue{Bytes: b.Bytes()}}), nil
+}
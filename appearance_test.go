@@ -0,0 +1,174 @@
+package gofpdi
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Fill a form and read the filled document
+func fillTestForm(t *testing.T, reader *PdfReader, fill func(filler *FormFiller) error) *PdfReader {
+	t.Helper()
+
+	filler, err := NewFormFiller(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fill(filler); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := filler.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	filled, err := NewPdfReaderFromStream(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filled
+}
+
+// Get the normal appearance (a stream, or a dictionary of streams by state) of a widget annotation
+func normalAppearance(t *testing.T, reader *PdfReader, id int) *PdfValue {
+	t.Helper()
+
+	widget, err := reader.Object(id, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ap, err := reader.Resolve(widget.Key("/AP"))
+	if err != nil {
+		t.Fatalf("widget %d has no appearance: %v", id, err)
+	}
+	normal, err := reader.Resolve(ap.Key("/N"))
+	if err != nil {
+		t.Fatalf("widget %d has no normal appearance: %v", id, err)
+	}
+
+	return normal
+}
+
+// Get the width and height of a rectangle
+func boxSize(box *PdfValue) (float64, float64) {
+	llx, _ := box.Index(0).AsFloat()
+	lly, _ := box.Index(1).AsFloat()
+	urx, _ := box.Index(2).AsFloat()
+	ury, _ := box.Index(3).AsFloat()
+	return urx - llx, ury - lly
+}
+
+func TestTextAppearance(t *testing.T) {
+	reader := readTestFile(t, "form.pdf")
+	filled := fillTestForm(t, reader, func(filler *FormFiller) error {
+		return filler.SetText("person.name", "Zoë")
+	})
+
+	// person.name is widget 14, with the /DA of its parent and the Helvetica font of the form's resources
+	normal := normalAppearance(t, filled, 14)
+	content, err := filled.DecodeStream(normal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte("/Helv 10 Tf 0 g")) || !bytes.Contains(content, []byte("(Zo\\353) Tj")) {
+		t.Errorf("appearance stream %q", content)
+	}
+	if w, h := boxSize(normal.Key("/BBox")); w != 200 || h != 20 {
+		t.Errorf("/BBox %vx%v, want the size of the widget", w, h)
+	}
+	if font := normal.Key("/Resources").Key("/Font").Key("/Helv"); !font.IsRef() {
+		t.Errorf("/Helv is %v, want a reference to the font of the form", font)
+	}
+}
+
+func TestRotatedTextAppearance(t *testing.T) {
+	reader := readTestFile(t, "form-rotated.pdf")
+	filled := fillTestForm(t, reader, func(filler *FormFiller) error {
+		if err := filler.SetText("rot", "Up"); err != nil {
+			return err
+		}
+		return filler.SetText("mac", "Mac")
+	})
+
+	// The text of the widget rotated by 90 degrees runs along its long side
+	rot := normalAppearance(t, filled, 9)
+	if m := rot.Key("/Matrix"); m.Len() != 6 || m.Index(1).Int != 1 || m.Index(2).Int != -1 {
+		t.Errorf("/Matrix %v, want a rotation by 90 degrees", m)
+	}
+	if w, h := boxSize(rot.Key("/BBox")); w != 200 || h != 20 {
+		t.Errorf("/BBox %vx%v, want width and height swapped", w, h)
+	}
+
+	// The MacRoman encoded font of the form is replaced by Helvetica
+	mac := normalAppearance(t, filled, 10)
+	font, err := filled.Resolve(mac.Key("/Resources").Key("/Font").Key("/Mac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base, _ := font.Key("/BaseFont").AsName(); base != "/Helvetica" {
+		t.Errorf("font %s, want /Helvetica", base)
+	}
+}
+
+func TestButtonAppearance(t *testing.T) {
+	reader := readTestFile(t, "form-radio.pdf")
+	filled := fillTestForm(t, reader, func(filler *FormFiller) error {
+		return filler.SetRadio("color", "A")
+	})
+
+	// Only the first button has the state A; the others get no on appearance
+	fields, err := filled.TerminalFormFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, widget := range fields["color"].Widgets {
+		want := "Off"
+		if i == 0 {
+			want = "A"
+		}
+		if widget.State != want {
+			t.Errorf("widget %d state %s, want %s", i, widget.State, want)
+		}
+	}
+
+	// A checkbox without appearances gets a check mark for its on state
+	reader = readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm << /Fields [4 0 R] >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Annots [4 0 R] >>",
+		"<< /FT /Btn /T (check) /Subtype /Widget /Rect [100 100 120 120] /P 3 0 R >>",
+	)
+	filled = fillTestForm(t, reader, func(filler *FormFiller) error {
+		return filler.SetCheckbox("check", true)
+	})
+
+	normal := normalAppearance(t, filled, 4)
+	if normal.Key("/Yes") == nil || normal.Key("/Off") == nil {
+		t.Fatalf("states %v, want /Yes and /Off", normal.Keys())
+	}
+	content, err := filled.DecodeStream(normal.Key("/Yes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte("Tf")) || !bytes.Contains(content, []byte("Tj")) {
+		t.Errorf("on appearance %q does not show a check mark", content)
+	}
+}
+
+func TestParseDefaultAppearance(t *testing.T) {
+	tests := []struct {
+		da   string
+		want defaultAppearance
+	}{
+		{"/Helv 12 Tf 0 g", defaultAppearance{"/Helv", 12, "0 g"}},
+		{"0 0 1 rg /Cour 0 Tf", defaultAppearance{"/Cour", 0, "0 0 1 rg"}},
+		{"", defaultAppearance{"/Helv", 0, "0 g"}},
+	}
+
+	for _, test := range tests {
+		if got := parseDefaultAppearance(test.da); got != test.want {
+			t.Errorf("parseDefaultAppearance(%q) = %+v, want %+v", test.da, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package gofpdi
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Default memory bound of the parsed object cache of a PdfReader
+const defaultObjectCacheSize = 32 << 20
+
+// Estimated overhead of a PdfValue (struct fields, map and slice headers) for cache accounting
+const pdfValueOverhead = 200
+
+// WithObjectCacheSize sets the (estimated) number of bytes the reader may use to cache parsed objects and
+// decoded object streams.  The least recently used entries are evicted when the limit is reached.
+// A size of 0 disables the cache.
+func WithObjectCacheSize(size int64) ReaderOption {
+	return func(r *PdfReader) {
+		r.cacheSize = size
+	}
+}
+
+// CacheStats holds the counters of the object cache of a PdfReader
+type CacheStats struct {
+	Hits      int64 // lookups served from the cache
+	Misses    int64 // lookups that had to parse the object (or decode the object stream)
+	Evictions int64 // entries removed to stay within the size limit
+	Entries   int   // entries currently cached
+	Bytes     int64 // estimated size of the entries currently cached
+	MaxBytes  int64 // size limit
+}
+
+// CacheStats returns the counters of the object cache
+func (this *PdfReader) CacheStats() CacheStats {
+	if this.cache == nil {
+		return CacheStats{MaxBytes: this.cacheSize}
+	}
+	return this.cache.stats()
+}
+
+// Cache key of an object (id and generation) or of a decoded object stream (id)
+type cacheKey struct {
+	id     int
+	gen    int
+	objStm bool
+}
+
+type cacheEntry struct {
+	key   cacheKey
+	value interface{}
+	size  int64
+}
+
+// A decoded object stream: its data and the id and offset (relative to /First) of each object
+type objectStream struct {
+	data    []byte
+	first   int
+	ids     []int
+	offsets []int
+}
+
+// LRU cache of parsed objects and decoded object streams, bounded by an estimated size in bytes.
+// Cached values are shared between callers and must not be modified.
+type objectCache struct {
+	mu        sync.Mutex
+	maxBytes  int64
+	bytes     int64
+	entries   map[cacheKey]*list.Element
+	lru       *list.List
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+func newObjectCache(maxBytes int64) *objectCache {
+	return &objectCache{
+		maxBytes: maxBytes,
+		entries:  make(map[cacheKey]*list.Element, 0),
+		lru:      list.New(),
+	}
+}
+
+// Look up an entry, counting a hit or a miss
+func (this *objectCache) get(key cacheKey) (interface{}, bool) {
+	if this == nil {
+		return nil, false
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if el, ok := this.entries[key]; ok {
+		this.hits++
+		this.lru.MoveToFront(el)
+		return el.Value.(*cacheEntry).value, true
+	}
+
+	this.misses++
+	return nil, false
+}
+
+// Add an entry, evicting the least recently used entries if the cache is full
+func (this *objectCache) put(key cacheKey, value interface{}, size int64) {
+	if this == nil || size > this.maxBytes {
+		return
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if el, ok := this.entries[key]; ok {
+		// Another goroutine parsed the same object in the meantime
+		this.lru.MoveToFront(el)
+		return
+	}
+
+	this.entries[key] = this.lru.PushFront(&cacheEntry{key: key, value: value, size: size})
+	this.bytes += size
+
+	for this.bytes > this.maxBytes {
+		el := this.lru.Back()
+		entry := el.Value.(*cacheEntry)
+		this.lru.Remove(el)
+		delete(this.entries, entry.key)
+		this.bytes -= entry.size
+		this.evictions++
+	}
+}
+
+func (this *objectCache) stats() CacheStats {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	return CacheStats{
+		Hits:      this.hits,
+		Misses:    this.misses,
+		Evictions: this.evictions,
+		Entries:   len(this.entries),
+		Bytes:     this.bytes,
+		MaxBytes:  this.maxBytes,
+	}
+}
+
+// Estimate the memory used by a parsed value
+func pdfValueSize(value *PdfValue) int64 {
+	if value == nil {
+		return 0
+	}
+
+	size := int64(pdfValueOverhead + len(value.String) + len(value.Token) + len(value.Bytes))
+
+	for k, v := range value.Dictionary {
+		size += int64(len(k)) + pdfValueSize(v)
+	}
+	for _, v := range value.Array {
+		size += pdfValueSize(v)
+	}
+
+	return size + pdfValueSize(value.Value) + pdfValueSize(value.Stream)
+}
+
+// Estimate the memory used by a decoded object stream
+func (this *objectStream) size() int64 {
+	return int64(len(this.data) + 16*len(this.ids) + pdfValueOverhead)
+}
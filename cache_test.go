@@ -0,0 +1,62 @@
+package gofpdi
+
+import "testing"
+
+func TestObjectCacheEviction(t *testing.T) {
+	cache := newObjectCache(100)
+	a, b, c := cacheKey{id: 1}, cacheKey{id: 2}, cacheKey{id: 3}
+
+	cache.put(a, "a", 60)
+	cache.put(b, "b", 30)
+	if v, ok := cache.get(a); !ok || v != "a" {
+		t.Fatalf("get(a) = %v, %v", v, ok)
+	}
+
+	// b is the least recently used entry
+	cache.put(c, "c", 30)
+	if _, ok := cache.get(b); ok {
+		t.Error("b was not evicted")
+	}
+	if _, ok := cache.get(a); !ok {
+		t.Error("a was evicted")
+	}
+
+	// Entries larger than the cache are not added
+	cache.put(cacheKey{id: 4}, "d", 101)
+
+	want := CacheStats{Hits: 2, Misses: 1, Evictions: 1, Entries: 2, Bytes: 90, MaxBytes: 100}
+	if stats := cache.stats(); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestReaderObjectCache(t *testing.T) {
+	reader := readTestFile(t, "xref-stream.pdf")
+
+	first, err := reader.Object(4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := reader.CacheStats()
+	second, err := reader.Object(4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("object was parsed again")
+	}
+	if stats := reader.CacheStats(); stats.Hits <= before.Hits {
+		t.Errorf("no cache hit: %+v", stats)
+	}
+
+	// Without a cache every lookup parses the object
+	reader = readTestFile(t, "xref-stream.pdf", WithObjectCacheSize(0))
+	first, _ = reader.Object(4, 0)
+	second, _ = reader.Object(4, 0)
+	if first == nil || first == second {
+		t.Error("object was cached with a cache size of 0")
+	}
+	if stats := reader.CacheStats(); stats.Entries != 0 || stats.MaxBytes != 0 {
+		t.Errorf("stats = %+v, want an empty cache", stats)
+	}
+}
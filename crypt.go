@@ -0,0 +1,627 @@
+package gofpdi
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+
+	"github.com/pkg/errors"
+)
+
+// Padding string used by the standard security handler (PDF 32000-1:2008, 7.6.3.3, Algorithm 2)
+var passwordPadding = []byte{
+	0x28, 0xbf, 0x4e, 0x5e, 0x4e, 0x75, 0x8a, 0x41, 0x64, 0x00, 0x4e, 0x56, 0xff, 0xfa, 0x01, 0x08,
+	0x2e, 0x2e, 0x00, 0xb6, 0xd0, 0x68, 0x3e, 0x80, 0x2f, 0x0c, 0xa9, 0xfe, 0x64, 0x53, 0x69, 0x7a,
+}
+
+// Crypt filter methods
+const (
+	cryptMethodNone  = "None"
+	cryptMethodRC4   = "RC4"
+	cryptMethodAESV2 = "AESV2"
+	cryptMethodAESV3 = "AESV3"
+)
+
+// State of the standard security handler for an encrypted document
+type pdfCrypt struct {
+	v               int
+	r               int
+	keyLength       int
+	o               []byte
+	u               []byte
+	oe              []byte
+	ue              []byte
+	p               int32
+	id              []byte
+	key             []byte
+	stmMethod       string
+	strMethod       string
+	encryptMetadata bool
+	encryptObjId    int
+}
+
+// Read the /Encrypt dictionary from the trailer and authenticate with the configured password
+func (this *PdfReader) readEncryption() error {
+	encryptSpec, ok := this.trailer.Dictionary["/Encrypt"]
+	if !ok {
+		return nil
+	}
+
+	crypt := &pdfCrypt{encryptMetadata: true}
+
+	encrypt := encryptSpec
+	if encryptSpec.Type == PDF_TYPE_OBJREF {
+		crypt.encryptObjId = encryptSpec.Id
+
+		res, err := this.resolveObject(encryptSpec)
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve encrypt dictionary")
+		}
+		encrypt = res.Value
+	}
+
+	if encrypt == nil || encrypt.Type != PDF_TYPE_DICTIONARY {
+		return errors.Wrap(ErrUnsupportedEncryption, "Encrypt dictionary is not a dictionary")
+	}
+
+	if filter, ok := encrypt.Dictionary["/Filter"]; !ok || filter.Token != "/Standard" {
+		return errors.Wrap(ErrUnsupportedEncryption, "Only the /Standard security handler is supported")
+	}
+
+	var err error
+	if crypt.v, err = this.readEncryptInt(encrypt, "/V"); err != nil {
+		return err
+	}
+	if crypt.r, err = this.readEncryptInt(encrypt, "/R"); err != nil {
+		return err
+	}
+	p, err := this.readEncryptInt(encrypt, "/P")
+	if err != nil {
+		return err
+	}
+	crypt.p = int32(p)
+
+	if crypt.o, err = this.readEncryptString(encrypt, "/O"); err != nil {
+		return err
+	}
+	if crypt.u, err = this.readEncryptString(encrypt, "/U"); err != nil {
+		return err
+	}
+
+	// File identifier (first element of /ID in the trailer)
+	if id, ok := this.trailer.Dictionary["/ID"]; ok && id.Type == PDF_TYPE_ARRAY && len(id.Array) > 0 {
+		crypt.id = pdfStringBytes(id.Array[0])
+	}
+
+	if em, ok := encrypt.Dictionary["/EncryptMetadata"]; ok && em.Type == PDF_TYPE_BOOLEAN {
+		crypt.encryptMetadata = em.Bool
+	}
+
+	// Key length in bytes
+	crypt.keyLength = 5
+	if length, ok := encrypt.Dictionary["/Length"]; ok && length.Int > 0 {
+		crypt.keyLength = length.Int / 8
+	}
+
+	switch crypt.v {
+	case 1:
+		crypt.keyLength = 5
+		crypt.stmMethod = cryptMethodRC4
+		crypt.strMethod = cryptMethodRC4
+
+	case 2:
+		crypt.stmMethod = cryptMethodRC4
+		crypt.strMethod = cryptMethodRC4
+
+	case 4, 5:
+		if crypt.v == 4 {
+			crypt.keyLength = 16
+		} else {
+			crypt.keyLength = 32
+		}
+
+		crypt.stmMethod, err = crypt.filterMethod(encrypt, "/StmF")
+		if err != nil {
+			return err
+		}
+		crypt.strMethod, err = crypt.filterMethod(encrypt, "/StrF")
+		if err != nil {
+			return err
+		}
+
+	default:
+		return errors.Wrap(ErrUnsupportedEncryption, fmt.Sprintf("Encryption algorithm /V %d", crypt.v))
+	}
+
+	if crypt.keyLength < 5 || crypt.keyLength > 32 {
+		return errors.New(fmt.Sprintf("Invalid encryption key length: %d", crypt.keyLength))
+	}
+
+	if crypt.r >= 5 {
+		if crypt.oe, err = this.readEncryptString(encrypt, "/OE"); err != nil {
+			return err
+		}
+		if crypt.ue, err = this.readEncryptString(encrypt, "/UE"); err != nil {
+			return err
+		}
+		err = crypt.authenticateV5([]byte(this.password))
+	} else if crypt.r >= 2 {
+		err = crypt.authenticate([]byte(this.password))
+	} else {
+		err = errors.Wrap(ErrUnsupportedEncryption, fmt.Sprintf("Security handler revision /R %d", crypt.r))
+	}
+	if err == ErrInvalidPassword && this.password == "" {
+		err = ErrEncrypted
+	}
+	if err != nil {
+		return err
+	}
+
+	this.crypt = crypt
+
+	return nil
+}
+
+// Read a string entry of the encrypt dictionary as raw bytes
+func (this *PdfReader) readEncryptString(encrypt *PdfValue, key string) ([]byte, error) {
+	value, ok := encrypt.Dictionary[key]
+	if !ok {
+		return nil, errors.New("Encrypt dictionary is missing " + key)
+	}
+
+	return pdfStringBytes(value), nil
+}
+
+// Read an integer entry of the encrypt dictionary
+func (this *PdfReader) readEncryptInt(encrypt *PdfValue, key string) (int, error) {
+	value, ok := encrypt.Dictionary[key]
+	if !ok {
+		return 0, errors.Wrap(ErrUnsupportedEncryption, "Encrypt dictionary is missing "+key)
+	}
+	if value.Type != PDF_TYPE_NUMERIC {
+		return 0, errors.Wrap(ErrUnsupportedEncryption, "Encrypt dictionary has a non-numeric "+key)
+	}
+
+	return value.Int, nil
+}
+
+// Determine the crypt filter method for /StmF or /StrF of a /V 4 or /V 5 encrypt dictionary
+func (this *pdfCrypt) filterMethod(encrypt *PdfValue, key string) (string, error) {
+	name := "/Identity"
+	if f, ok := encrypt.Dictionary[key]; ok {
+		name = f.Token
+	}
+
+	if name == "/Identity" {
+		return cryptMethodNone, nil
+	}
+
+	cf, ok := encrypt.Dictionary["/CF"]
+	if !ok {
+		return "", errors.New("Encrypt dictionary is missing /CF")
+	}
+
+	filter, ok := cf.Dictionary[name]
+	if !ok {
+		return "", errors.New("Crypt filter not found: " + name)
+	}
+
+	cfm := "/None"
+	if m, ok := filter.Dictionary["/CFM"]; ok {
+		cfm = m.Token
+	}
+
+	switch cfm {
+	case "/None":
+		return cryptMethodNone, nil
+	case "/V2":
+		return cryptMethodRC4, nil
+	case "/AESV2":
+		return cryptMethodAESV2, nil
+	case "/AESV3":
+		return cryptMethodAESV3, nil
+	}
+
+	return "", errors.Wrap(ErrUnsupportedEncryption, "Crypt filter method "+cfm)
+}
+
+// Pad or truncate a password to 32 bytes
+func padPassword(password []byte) []byte {
+	result := make([]byte, 32)
+	n := copy(result, password)
+	copy(result[n:], passwordPadding)
+	return result
+}
+
+// Compute the file encryption key from a user password (Algorithm 2)
+func (this *pdfCrypt) computeKey(password []byte) []byte {
+	h := md5.New()
+	h.Write(padPassword(password))
+	h.Write(this.o)
+
+	p := make([]byte, 4)
+	binary.LittleEndian.PutUint32(p, uint32(this.p))
+	h.Write(p)
+
+	h.Write(this.id)
+
+	if this.r >= 4 && !this.encryptMetadata {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+
+	key := h.Sum(nil)
+
+	if this.r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum := md5.Sum(key[:this.keyLength])
+			key = sum[:]
+		}
+	}
+
+	return key[:this.keyLength]
+}
+
+// Compute the /U value for a given encryption key (Algorithms 4 and 5)
+func (this *pdfCrypt) computeU(key []byte) []byte {
+	if this.r == 2 {
+		return rc4Crypt(key, passwordPadding)
+	}
+
+	h := md5.New()
+	h.Write(passwordPadding)
+	h.Write(this.id)
+	result := rc4Crypt(key, h.Sum(nil))
+
+	for i := 1; i <= 19; i++ {
+		result = rc4Crypt(xorKey(key, byte(i)), result)
+	}
+
+	return result
+}
+
+// Check a user password and set the file encryption key on success (Algorithm 6)
+func (this *pdfCrypt) authenticateUser(password []byte) bool {
+	key := this.computeKey(password)
+	u := this.computeU(key)
+
+	n := 32
+	if this.r >= 3 {
+		n = 16
+	}
+	if len(this.u) < n || !bytes.Equal(u[:n], this.u[:n]) {
+		return false
+	}
+
+	this.key = key
+	return true
+}
+
+// Authenticate as user or owner for revisions 2-4 (Algorithms 6 and 7)
+func (this *pdfCrypt) authenticate(password []byte) error {
+	if this.authenticateUser(password) {
+		return nil
+	}
+
+	// Recover the user password from the owner password and /O
+	sum := md5.Sum(padPassword(password))
+	ownerKey := sum[:]
+	if this.r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(ownerKey)
+			ownerKey = sum[:]
+		}
+	}
+	ownerKey = ownerKey[:this.keyLength]
+
+	userPassword := this.o
+	if this.r == 2 {
+		userPassword = rc4Crypt(ownerKey, userPassword)
+	} else {
+		for i := 19; i >= 0; i-- {
+			userPassword = rc4Crypt(xorKey(ownerKey, byte(i)), userPassword)
+		}
+	}
+
+	if this.authenticateUser(userPassword) {
+		return nil
+	}
+
+	return ErrInvalidPassword
+}
+
+// Authenticate as owner or user for revisions 5 and 6 (Algorithm 2.A)
+func (this *pdfCrypt) authenticateV5(password []byte) error {
+	if len(password) > 127 {
+		password = password[:127]
+	}
+
+	if len(this.o) < 48 || len(this.u) < 48 {
+		return errors.New("Invalid /O or /U length in encrypt dictionary")
+	}
+
+	var intermediate []byte
+
+	if bytes.Equal(this.hashV5(password, this.o[32:40], this.u[:48]), this.o[:32]) {
+		// Owner password
+		intermediate = this.hashV5(password, this.o[40:48], this.u[:48])
+		this.key = aesDecryptNoPadding(intermediate, this.oe)
+	} else if bytes.Equal(this.hashV5(password, this.u[32:40], nil), this.u[:32]) {
+		// User password
+		intermediate = this.hashV5(password, this.u[40:48], nil)
+		this.key = aesDecryptNoPadding(intermediate, this.ue)
+	} else {
+		return ErrInvalidPassword
+	}
+
+	if len(this.key) != 32 {
+		return errors.New("Failed to decrypt file encryption key")
+	}
+
+	return nil
+}
+
+// Compute the hash used by revisions 5 and 6 (Algorithm 2.B for /R 6)
+func (this *pdfCrypt) hashV5(password, salt, userKey []byte) []byte {
+	h := sha256.New()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(userKey)
+	k := h.Sum(nil)
+
+	if this.r < 6 {
+		return k
+	}
+
+	for i := 0; ; {
+		// Input sequence repeated 64 times
+		var seq bytes.Buffer
+		for j := 0; j < 64; j++ {
+			seq.Write(password)
+			seq.Write(k)
+			seq.Write(userKey)
+		}
+
+		block, _ := aes.NewCipher(k[:16])
+		e := make([]byte, seq.Len())
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, seq.Bytes())
+
+		// Select the next hash function by the remainder of the first 16 bytes of e
+		sum := 0
+		for _, b := range e[:16] {
+			sum += int(b)
+		}
+
+		var next hash.Hash
+		switch sum % 3 {
+		case 0:
+			next = sha256.New()
+		case 1:
+			next = sha512.New384()
+		case 2:
+			next = sha512.New()
+		}
+		next.Write(e)
+		k = next.Sum(nil)
+
+		i++
+		if i >= 64 && int(e[len(e)-1]) <= i-32 {
+			break
+		}
+	}
+
+	return k[:32]
+}
+
+// Compute the key for a specific object (Algorithm 1)
+func (this *pdfCrypt) objectKey(id, gen int, method string) []byte {
+	if method == cryptMethodAESV3 {
+		return this.key
+	}
+
+	h := md5.New()
+	h.Write(this.key)
+	h.Write([]byte{byte(id), byte(id >> 8), byte(id >> 16), byte(gen), byte(gen >> 8)})
+	if method == cryptMethodAESV2 {
+		h.Write([]byte("sAlT"))
+	}
+
+	n := len(this.key) + 5
+	if n > 16 {
+		n = 16
+	}
+
+	return h.Sum(nil)[:n]
+}
+
+// Decrypt data belonging to object id/gen with the given crypt method
+func (this *pdfCrypt) decrypt(id, gen int, data []byte, method string) ([]byte, error) {
+	switch method {
+	case cryptMethodNone:
+		return data, nil
+	case cryptMethodRC4:
+		return rc4Crypt(this.objectKey(id, gen, method), data), nil
+	case cryptMethodAESV2, cryptMethodAESV3:
+		return aesDecrypt(this.objectKey(id, gen, method), data)
+	}
+
+	return nil, errors.New("Unsupported crypt method: " + method)
+}
+
+// Decrypt all strings and the stream (if any) of an indirect object in place
+func (this *pdfCrypt) decryptObject(obj *PdfValue) error {
+	// The encrypt dictionary itself is never encrypted
+	if obj.Id == this.encryptObjId {
+		return nil
+	}
+
+	if err := this.decryptStrings(obj.Id, obj.Gen, obj.Value); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to decrypt strings of object %d", obj.Id))
+	}
+
+	if obj.Type != PDF_TYPE_STREAM || obj.Stream == nil {
+		return nil
+	}
+
+	dict := obj.Value.Dictionary
+
+	// Cross-reference streams are not encrypted
+	if t, ok := dict["/Type"]; ok && t.Token == "/XRef" {
+		return nil
+	}
+
+	// Metadata streams are left unencrypted when /EncryptMetadata is false
+	if t, ok := dict["/Type"]; ok && t.Token == "/Metadata" && !this.encryptMetadata {
+		return nil
+	}
+
+	// Streams with an explicit /Crypt filter use the named crypt filter (only /Identity is supported)
+	if name, ok := streamCryptFilter(dict); ok {
+		if name != "/Identity" {
+			return errors.Wrap(ErrUnsupportedEncryption, fmt.Sprintf("Crypt filter %s of object %d", name, obj.Id))
+		}
+		return nil
+	}
+
+	data, err := this.decrypt(obj.Id, obj.Gen, obj.Stream.Bytes, this.stmMethod)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to decrypt stream of object %d", obj.Id))
+	}
+	obj.Stream.Bytes = data
+
+	return nil
+}
+
+// Get the name of the crypt filter of a stream with a /Crypt filter, /Identity if its decode parameters name none.
+// The /Crypt filter must come first in the list of filters.
+func streamCryptFilter(dict map[string]*PdfValue) (string, bool) {
+	f, ok := dict["/Filter"]
+	if !ok {
+		return "", false
+	}
+
+	parms := dict["/DecodeParms"]
+	if f.Type == PDF_TYPE_ARRAY {
+		if len(f.Array) == 0 || f.Array[0].Token != "/Crypt" {
+			return "", false
+		}
+		if parms != nil && parms.Type == PDF_TYPE_ARRAY {
+			if len(parms.Array) > 0 {
+				parms = parms.Array[0]
+			} else {
+				parms = nil
+			}
+		}
+	} else if f.Token != "/Crypt" {
+		return "", false
+	}
+
+	if parms == nil || parms.Type == PDF_TYPE_NULL {
+		return "/Identity", true
+	}
+	if parms.Type != PDF_TYPE_DICTIONARY {
+		// Indirect decode parameters cannot be resolved here
+		return "(indirect)", true
+	}
+	if name, ok := parms.Dictionary["/Name"]; ok {
+		return name.Token, true
+	}
+
+	return "/Identity", true
+}
+
+// Recursively decrypt strings within a value
+func (this *pdfCrypt) decryptStrings(id, gen int, value *PdfValue) error {
+	if value == nil {
+		return nil
+	}
+
+	switch value.Type {
+	case PDF_TYPE_STRING, PDF_TYPE_HEX:
+		data, err := this.decrypt(id, gen, pdfStringBytes(value), this.strMethod)
+		if err != nil {
+			return err
+		}
+		setPdfStringBytes(value, data)
+
+	case PDF_TYPE_ARRAY:
+		for i := 0; i < len(value.Array); i++ {
+			if err := this.decryptStrings(id, gen, value.Array[i]); err != nil {
+				return err
+			}
+		}
+
+	case PDF_TYPE_DICTIONARY:
+		for _, v := range value.Dictionary {
+			if err := this.decryptStrings(id, gen, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Encrypt or decrypt data with RC4
+func rc4Crypt(key, data []byte) []byte {
+	c, _ := rc4.NewCipher(key)
+	result := make([]byte, len(data))
+	c.XORKeyStream(result, data)
+	return result
+}
+
+// XOR each byte of a key with n
+func xorKey(key []byte, n byte) []byte {
+	result := make([]byte, len(key))
+	for i := range key {
+		result[i] = key[i] ^ n
+	}
+	return result
+}
+
+// Decrypt AES-CBC data prefixed with a 16 byte initialization vector and remove PKCS#5 padding
+func aesDecrypt(key, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New(fmt.Sprintf("Invalid AES encrypted data length: %d", len(data)))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create AES cipher")
+	}
+
+	result := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(result, data[aes.BlockSize:])
+
+	padding := int(result[len(result)-1])
+	if padding < 1 || padding > aes.BlockSize {
+		return nil, errors.New("Invalid AES padding")
+	}
+
+	return result[:len(result)-padding], nil
+}
+
+// Decrypt AES-256-CBC data with a zero initialization vector and no padding (used for /OE and /UE)
+func aesDecryptNoPadding(key, data []byte) []byte {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil
+	}
+
+	result := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(result, data)
+	return result
+}
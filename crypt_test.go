@@ -0,0 +1,151 @@
+package gofpdi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestRC4Crypt(t *testing.T) {
+	tests := []struct {
+		key, data, want string
+	}{
+		{"Key", "Plaintext", "bbf316e8d940af0ad3"},
+		{"Wiki", "pedia", "1021bf0420"},
+		{"Secret", "Attack at dawn", "45a01f645fc35b383552544b9bf5"},
+	}
+
+	for _, test := range tests {
+		got := hex.EncodeToString(rc4Crypt([]byte(test.key), []byte(test.data)))
+		if got != test.want {
+			t.Errorf("rc4Crypt(%q, %q) = %s, want %s", test.key, test.data, got, test.want)
+		}
+
+		// RC4 is symmetric
+		want, _ := hex.DecodeString(test.want)
+		if got := string(rc4Crypt([]byte(test.key), want)); got != test.data {
+			t.Errorf("rc4Crypt(%q, %s) = %q, want %q", test.key, test.want, got, test.data)
+		}
+	}
+}
+
+func TestAESDecrypt(t *testing.T) {
+	// The first block is the CBC example of NIST SP 800-38A (F.2.1 and F.2.5), the second one a block of padding
+	tests := []struct {
+		key, data string
+	}{
+		{
+			"2b7e151628aed2a6abf7158809cf4f3c",
+			"000102030405060708090a0b0c0d0e0f7649abac8119b246cee98e9b12e9197d8964e0b149c10b7b682e6e39aaeb731c",
+		},
+		{
+			"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+			"000102030405060708090a0b0c0d0e0ff58c4c04d6e5f1ba779eabfb5f7bfbd6485a5c81519cf378fa36d42b8547edc0",
+		},
+	}
+	want, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+
+	for _, test := range tests {
+		key, _ := hex.DecodeString(test.key)
+		data, _ := hex.DecodeString(test.data)
+
+		got, err := aesDecrypt(key, data)
+		if err != nil {
+			t.Errorf("aesDecrypt with a %d bit key: %v", len(key)*8, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("aesDecrypt with a %d bit key = %x, want %x", len(key)*8, got, want)
+		}
+
+		// Data that is not a multiple of the block size
+		if _, err := aesDecrypt(key, data[:len(data)-1]); err == nil {
+			t.Errorf("aesDecrypt with a %d bit key accepted truncated data", len(key)*8)
+		}
+	}
+}
+
+func TestEncryptedDocuments(t *testing.T) {
+	// The encrypted content of aes-128-whitespace.pdf starts with whitespace, which belongs to the stream data
+	files := []string{"rc4-40.pdf", "rc4-128.pdf", "aes-128.pdf", "aes-128-whitespace.pdf", "aes-256-r5.pdf", "aes-256.pdf"}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile("testdata/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, password := range []string{"user", "owner"} {
+			reader, err := NewPdfReaderFromStream(bytes.NewReader(data), WithPassword(password))
+			if err != nil {
+				t.Errorf("%s with password %q: %v", file, password, err)
+				continue
+			}
+			if got := pageText(t, reader, 1); got != "Secret page one\n" {
+				t.Errorf("%s with password %q: page text %q", file, password, got)
+			}
+		}
+
+		if _, err := NewPdfReaderFromStream(bytes.NewReader(data)); !errors.Is(err, ErrEncrypted) {
+			t.Errorf("%s without password: got %v, want ErrEncrypted", file, err)
+		}
+		if _, err := NewPdfReaderFromStream(bytes.NewReader(data), WithPassword("wrong")); !errors.Is(err, ErrInvalidPassword) {
+			t.Errorf("%s with a wrong password: got %v, want ErrInvalidPassword", file, err)
+		}
+	}
+}
+
+func TestEncryptDictionaryMissingEntries(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/rc4-40.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rename each required entry, keeping the offsets of the objects
+	for _, key := range []string{"/V ", "/R ", "/P "} {
+		damaged := bytes.Replace(data, []byte(key), []byte("/X "), 1)
+		if bytes.Equal(damaged, data) {
+			t.Fatalf("%s not found", key)
+		}
+
+		if _, err := NewPdfReaderFromStream(bytes.NewReader(damaged), WithPassword("user")); !errors.Is(err, ErrUnsupportedEncryption) {
+			t.Errorf("encrypt dictionary without %s: got %v, want ErrUnsupportedEncryption", key, err)
+		}
+	}
+}
+
+func TestStreamCryptFilter(t *testing.T) {
+	name := func(token string) *PdfValue { return &PdfValue{Type: PDF_TYPE_TOKEN, Token: token} }
+	dict := func(entries map[string]*PdfValue) *PdfValue {
+		return &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: entries}
+	}
+
+	tests := []struct {
+		stream map[string]*PdfValue
+		name   string
+		ok     bool
+	}{
+		{map[string]*PdfValue{"/Filter": name("/FlateDecode")}, "", false},
+		{map[string]*PdfValue{"/Filter": name("/Crypt")}, "/Identity", true},
+		{map[string]*PdfValue{
+			"/Filter":      name("/Crypt"),
+			"/DecodeParms": dict(map[string]*PdfValue{"/Name": name("/StdCF")}),
+		}, "/StdCF", true},
+		{map[string]*PdfValue{
+			"/Filter":      {Type: PDF_TYPE_ARRAY, Array: []*PdfValue{name("/Crypt"), name("/FlateDecode")}},
+			"/DecodeParms": {Type: PDF_TYPE_ARRAY, Array: []*PdfValue{dict(map[string]*PdfValue{"/Name": name("/Identity")}), {Type: PDF_TYPE_NULL}}},
+		}, "/Identity", true},
+		{map[string]*PdfValue{
+			"/Filter": {Type: PDF_TYPE_ARRAY, Array: []*PdfValue{name("/FlateDecode"), name("/Crypt")}},
+		}, "", false},
+	}
+
+	for i, test := range tests {
+		got, ok := streamCryptFilter(test.stream)
+		if got != test.name || ok != test.ok {
+			t.Errorf("test %d: streamCryptFilter = %q, %v, want %q, %v", i, got, ok, test.name, test.ok)
+		}
+	}
+}
@@ -0,0 +1,693 @@
+package gofpdi
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Document writes a complete PDF document without a host generator such as gofpdf or gopdf: pages are added,
+// imported templates are placed on them, and Close writes the templates, the pages, the page tree, the catalog,
+// the cross-reference table and the trailer.
+//
+//	doc := gofpdi.NewDocument(w)
+//	tpl, err := doc.ImportPage(reader, 1, "/MediaBox")
+//	doc.AddPage(595.28, 841.89)
+//	err = doc.UseTemplate(tpl, 0, 0, 595.28, 0)
+//	err = doc.Close()
+//
+// Sizes and positions are in points (1/72 inch).  Like PdfWriter.UseTemplate, template positions are measured
+// from the top left corner of the page.
+type Document struct {
+	w       io.Writer
+	buf     *bytes.Buffer
+	offset  int         // number of bytes written to w
+	offsets map[int]int // byte offsets of the objects written so far, by object id
+	n       int         // id of the last object
+	pagesId int         // id of the page tree root
+	closed  bool
+
+	// A writer for each source document, in the order they were first imported from
+	readers   []*PdfReader
+	writers   map[*PdfReader]*PdfWriter
+	templates []*documentTemplate
+
+	pages []*documentPage
+
+	// Interactive form of the copied pages: the root fields of their widgets, the default appearance of the first
+	// source document that has one and the fonts of the default resources, by name
+	fields    []int
+	fieldIds  map[int]bool
+	formDA    *PdfValue
+	formFonts map[string]int
+}
+
+// A template of a Document, created by one of its writers
+type documentTemplate struct {
+	writer *PdfWriter
+	id     int // template id of the writer
+	objId  int // object id of the Form XObject, once written
+}
+
+// A page of a Document
+type documentPage struct {
+	id        int // object id of a copied page, which is written when it is copied
+	width     float64
+	height    float64
+	content   bytes.Buffer
+	templates map[int]bool // templates used by the page
+}
+
+// Version of the PDF header.  Imported pages are copied as they are, so they may use any feature of PDF 1.7.
+const documentVersion = "1.7"
+
+// NewDocument creates a document that is written to w when it is closed
+func NewDocument(w io.Writer) *Document {
+	// The page tree root is referenced by copied pages before it is written, so its id is reserved first
+	doc := &Document{
+		w:       w,
+		buf:     new(bytes.Buffer),
+		offsets: make(map[int]int, 0),
+		n:       1,
+		pagesId: 1,
+		writers: make(map[*PdfReader]*PdfWriter, 0),
+
+		fieldIds:  make(map[int]bool, 0),
+		formFonts: make(map[string]int, 0),
+	}
+
+	doc.buf.WriteString("%PDF-" + documentVersion + "\n%\xe2\xe3\xcf\xd3\n")
+
+	return doc
+}
+
+// ImportPage imports a page of a source document as a template (see PdfWriter.ImportPage) and returns its id
+func (this *Document) ImportPage(reader *PdfReader, pageno int, boxName string, opts ...ImportOption) (int, error) {
+	if this.closed {
+		return -1, errors.New("Document is closed")
+	}
+
+	writer, err := this.writer(reader)
+	if err != nil {
+		return -1, err
+	}
+
+	id, err := writer.ImportPage(reader, pageno, boxName, opts...)
+	if err != nil {
+		return -1, err
+	}
+
+	this.templates = append(this.templates, &documentTemplate{writer: writer, id: id})
+
+	return len(this.templates) - 1, nil
+}
+
+// TemplateSize returns the size of a template in points
+func (this *Document) TemplateSize(tplid int) (float64, float64, error) {
+	tpl, err := this.template(tplid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	size := tpl.writer.getTemplateSize(tpl.id, 0, 0)
+	return size["w"], size["h"], nil
+}
+
+// AddPage adds a page of the given size in points, which becomes the current page
+func (this *Document) AddPage(width, height float64) error {
+	if this.closed {
+		return errors.New("Document is closed")
+	}
+	if width <= 0 || height <= 0 {
+		return errors.New(fmt.Sprintf("Invalid page size %s x %s", formatNumber(width), formatNumber(height)))
+	}
+
+	this.pages = append(this.pages, &documentPage{width: width, height: height, templates: make(map[int]bool, 0)})
+
+	return nil
+}
+
+// UseTemplate draws a template on the current page with its top left corner at x, y.  If w or h is 0, it is
+// computed from the other so the aspect ratio of the template is kept; if both are 0, the template is drawn at its
+// own size.
+func (this *Document) UseTemplate(tplid int, x, y, w, h float64) error {
+	page, err := this.currentPage()
+	if err != nil {
+		return err
+	}
+
+	tpl, err := this.template(tplid)
+	if err != nil {
+		return err
+	}
+
+	_, scaleX, scaleY, tx, ty := tpl.writer.UseTemplate(tpl.id, x, y, w, h)
+
+	return this.drawTemplate(tplid, [6]float64{scaleX, 0, 0, scaleY, tx, ty + page.height})
+}
+
+// Draw a template on the current page, transformed by a matrix that maps the template at its own size, with its
+// lower left corner at the origin, onto the page
+func (this *Document) drawTemplate(tplid int, matrix [6]float64) error {
+	page, err := this.currentPage()
+	if err != nil {
+		return err
+	}
+
+	if _, err := this.template(tplid); err != nil {
+		return err
+	}
+
+	page.templates[tplid] = true
+	page.content.WriteString(fmt.Sprintf("q %s %s %s %s %s %s cm %s Do Q\n", formatNumber(matrix[0]), formatNumber(matrix[1]),
+		formatNumber(matrix[2]), formatNumber(matrix[3]), formatNumber(matrix[4]), formatNumber(matrix[5]),
+		documentTemplateName(tplid)))
+
+	return nil
+}
+
+// AddContent appends content stream operators to the current page.  Unlike UseTemplate, they use the coordinate
+// system of the page, with the origin at the bottom left corner.  The graphics state is saved and restored around
+// them.
+func (this *Document) AddContent(content string) error {
+	page, err := this.currentPage()
+	if err != nil {
+		return err
+	}
+
+	page.content.WriteString("q\n")
+	page.content.WriteString(content)
+	page.content.WriteString("\nQ\n")
+
+	return nil
+}
+
+// CopyPages appends pages of a source document as they are, with their contents, resources and annotations, rather
+// than as templates drawn on new pages.  Objects shared by pages of the same source document are written once.
+// References to pages of the source document that are not copied (e.g. link destinations) are replaced with null,
+// unless the pages were copied by an earlier call; copy all pages of a document with a single call to keep links
+// between them.  Article threads (/B), named destinations and the outline are not copied.
+//
+// Form fields with widgets on the copied pages are added to the interactive form of the document, along with the
+// fonts of their default resources.  Widgets of these fields on pages that are not copied are left out.  Fields of
+// different documents are not merged, even if they have the same name.
+func (this *Document) CopyPages(reader *PdfReader, pagenos ...int) error {
+	if this.closed {
+		return errors.New("Document is closed")
+	}
+
+	writer, err := this.writer(reader)
+	if err != nil {
+		return err
+	}
+	writer.r = reader
+	writer.SetNextObjectID(this.n + 1)
+
+	pages := make([]*pageNode, len(pagenos))
+	for i, pageno := range pagenos {
+		pages[i], err = reader.getPageNode(pageno)
+		if err != nil {
+			return pageError(pageno, errors.Wrap(err, "Failed to resolve page object"))
+		}
+	}
+
+	// References to pages that are not copied, and to their widgets (reachable from the fields they belong to), are
+	// written as null
+	if writer.page_obj_ids == nil {
+		writer.page_obj_ids, err = reader.pageObjectIds()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reserve the ids of the pages, so references between them point to the copies
+	reserved := make(map[int]int, len(pages))
+	for _, page := range pages {
+		if _, ok := writer.don_obj_stack[page.page.Id]; !ok {
+			writer.newObj(-1, true)
+			writer.don_obj_stack[page.page.Id] = &PdfValue{Type: PDF_TYPE_OBJREF, Id: page.page.Id, Gen: page.page.Gen, NewId: writer.n}
+			reserved[page.page.Id] = writer.n
+		}
+	}
+
+	for i, page := range pages {
+		// A page copied more than once gets a new object for each further copy, without the annotations of the
+		// first one, which cannot be on several pages
+		id, first := reserved[page.page.Id]
+		if first {
+			delete(reserved, page.page.Id)
+		} else {
+			writer.newObj(-1, true)
+			id = writer.n
+		}
+
+		dict := make(map[string]*PdfValue, len(page.page.Value.Dictionary)+len(inheritablePageAttributes))
+		for key, value := range page.page.Value.Dictionary {
+			dict[key] = value
+		}
+		for _, key := range inheritablePageAttributes {
+			if value, ok := page.attribute(key); ok {
+				dict[key] = value
+			}
+		}
+		// The page tree root is not an object of the source document, so its reference is written as is
+		dict["/Parent"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: fmt.Sprintf("%d 0 R", this.pagesId)}
+		delete(dict, "/B")
+		if !first {
+			delete(dict, "/Annots")
+		}
+
+		writer.newObj(id, false)
+		writer.writeValue(&PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: dict})
+		writer.out("")
+		writer.endObj()
+
+		if err := writer.putImportedObjects(reader); err != nil {
+			return pageError(pagenos[i], errors.Wrap(err, "Failed to put imported objects"))
+		}
+
+		this.pages = append(this.pages, &documentPage{id: id})
+	}
+
+	if err := this.copyFormFields(reader, writer, pages); err != nil {
+		return err
+	}
+
+	this.putWriterObjects(writer)
+
+	return this.flush()
+}
+
+// PageCount returns the number of pages added so far
+func (this *Document) PageCount() int {
+	return len(this.pages)
+}
+
+// Close writes the document.  The document cannot be changed afterwards.
+func (this *Document) Close() error {
+	if this.closed {
+		return errors.New("Document is closed")
+	}
+	this.closed = true
+
+	if len(this.pages) == 0 {
+		return errors.New("Document has no pages")
+	}
+
+	if err := this.putTemplates(); err != nil {
+		return err
+	}
+
+	writer := &PdfWriter{keep_obj_ids: true, current_obj: &PdfObject{buffer: this.buf}}
+
+	kids := make([]*PdfValue, 0, len(this.pages))
+	for _, page := range this.pages {
+		if page.id != 0 {
+			kids = append(kids, &PdfValue{Type: PDF_TYPE_OBJREF, Id: page.id})
+		} else {
+			ref, err := this.putPage(writer, page)
+			if err != nil {
+				return err
+			}
+			kids = append(kids, ref)
+		}
+	}
+
+	this.putObject(writer, this.pagesId, &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+		"/Type":  {Type: PDF_TYPE_TOKEN, Token: "/Pages"},
+		"/Kids":  {Type: PDF_TYPE_ARRAY, Array: kids},
+		"/Count": {Type: PDF_TYPE_NUMERIC, Int: len(kids)},
+	}})
+
+	this.n++
+	catalogId := this.n
+	catalog := &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+		"/Type":  {Type: PDF_TYPE_TOKEN, Token: "/Catalog"},
+		"/Pages": {Type: PDF_TYPE_OBJREF, Id: this.pagesId},
+	}}
+	if len(this.fields) > 0 {
+		catalog.Dictionary["/AcroForm"] = this.acroForm()
+	}
+	this.putObject(writer, catalogId, catalog)
+
+	this.putXref(writer, catalogId)
+
+	return this.flush()
+}
+
+// Add the root fields of the widgets on copied pages to the interactive form, with the default appearance and the
+// fonts of the default resources of the source document
+func (this *Document) copyFormFields(reader *PdfReader, writer *PdfWriter, pages []*pageNode) error {
+	found := false
+	for _, page := range pages {
+		for _, widget := range pageWidgets(reader, page) {
+			id := writer.importObjRef(fieldRoot(reader, widget))
+			if !this.fieldIds[id] {
+				this.fieldIds[id] = true
+				this.fields = append(this.fields, id)
+			}
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	if acroForm, err := reader.Resolve(reader.catalog.Value.Key("/AcroForm")); err == nil {
+		if this.formDA == nil {
+			if da, err := reader.Resolve(acroForm.Key("/DA")); err == nil {
+				this.formDA = da.direct()
+			}
+		}
+
+		if dr, err := reader.Resolve(acroForm.Key("/DR")); err == nil {
+			if fonts, err := reader.Resolve(dr.Key("/Font")); err == nil {
+				for _, name := range fonts.Keys() {
+					if font := fonts.Key(name); font.IsRef() {
+						if _, ok := this.formFonts[name]; !ok {
+							this.formFonts[name] = writer.importObjRef(font)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return writer.putImportedObjects(reader)
+}
+
+// Build the interactive form dictionary of the copied fields
+func (this *Document) acroForm() *PdfValue {
+	fields := make([]*PdfValue, 0, len(this.fields))
+	for _, id := range this.fields {
+		fields = append(fields, &PdfValue{Type: PDF_TYPE_OBJREF, Id: id})
+	}
+
+	dict := map[string]*PdfValue{
+		"/Fields": {Type: PDF_TYPE_ARRAY, Array: fields},
+	}
+	if this.formDA != nil {
+		dict["/DA"] = this.formDA
+	}
+	if len(this.formFonts) > 0 {
+		fonts := make(map[string]*PdfValue, len(this.formFonts))
+		for name, id := range this.formFonts {
+			fonts[name] = &PdfValue{Type: PDF_TYPE_OBJREF, Id: id}
+		}
+		dict["/DR"] = &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+			"/Font": {Type: PDF_TYPE_DICTIONARY, Dictionary: fonts},
+		}}
+	}
+
+	return &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: dict}
+}
+
+// Get references to the widget annotations of a page.  Annotations that cannot be resolved are skipped.
+func pageWidgets(reader *PdfReader, page *pageNode) []*PdfValue {
+	widgets := make([]*PdfValue, 0)
+
+	annots, err := reader.Resolve(page.page.Value.Key("/Annots"))
+	if err != nil {
+		return widgets
+	}
+
+	array, _ := annots.AsArray()
+	for _, ref := range array {
+		if !ref.IsRef() {
+			continue
+		}
+		annot, err := reader.Resolve(ref)
+		if err != nil {
+			continue
+		}
+		if subtype, _ := annot.Key("/Subtype").AsName(); subtype == "/Widget" {
+			widgets = append(widgets, ref)
+		}
+	}
+
+	return widgets
+}
+
+// Get the object ids of the pages of a document and of the widget annotations on them, mapped to the id of the page
+// they are on.  The map is built the first time this is called, which resolves every page.
+func (this *PdfReader) pageObjectIds() (map[int]int, error) {
+	this.pagesMu.Lock()
+	ids, walked := this.pageObjects, this.pagesWalked
+	this.pagesMu.Unlock()
+
+	if ids != nil {
+		return ids, nil
+	}
+	if !walked {
+		if err := this.loadAllPages(); err != nil {
+			return nil, err
+		}
+	}
+
+	this.pagesMu.Lock()
+	pages := this.pages
+	this.pagesMu.Unlock()
+
+	ids = make(map[int]int, len(pages))
+	for _, page := range pages {
+		ids[page.page.Id] = page.page.Id
+		for _, widget := range pageWidgets(this, page) {
+			ids[widget.Id] = page.page.Id
+		}
+	}
+
+	this.pagesMu.Lock()
+	this.pageObjects = ids
+	this.pagesMu.Unlock()
+
+	return ids, nil
+}
+
+// Get a reference to the root field of a widget, following its /Parent chain
+func fieldRoot(reader *PdfReader, widget *PdfValue) *PdfValue {
+	ref := widget
+	for i := 0; i < maxReferenceChain; i++ {
+		obj, err := reader.Resolve(ref)
+		if err != nil {
+			break
+		}
+
+		parent := obj.Key("/Parent")
+		if !parent.IsRef() {
+			break
+		}
+		ref = parent
+	}
+
+	return ref
+}
+
+// Get the writer of a source document
+func (this *Document) writer(reader *PdfReader) (*PdfWriter, error) {
+	if writer, ok := this.writers[reader]; ok {
+		return writer, nil
+	}
+
+	writer, err := NewPdfWriter("")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create writer")
+	}
+	this.writers[reader] = writer
+	this.readers = append(this.readers, reader)
+
+	return writer, nil
+}
+
+// Get the current page
+func (this *Document) currentPage() (*documentPage, error) {
+	if this.closed {
+		return nil, errors.New("Document is closed")
+	}
+	if len(this.pages) == 0 {
+		return nil, errors.New("No page has been added")
+	}
+
+	page := this.pages[len(this.pages)-1]
+	if page.id != 0 {
+		return nil, errors.New("Cannot add content to a copied page")
+	}
+
+	return page, nil
+}
+
+// Look up a template by its id
+func (this *Document) template(tplid int) (*documentTemplate, error) {
+	if tplid < 0 || tplid >= len(this.templates) {
+		return nil, errors.New(fmt.Sprintf("Invalid template id %d", tplid))
+	}
+
+	return this.templates[tplid], nil
+}
+
+// Resource name of a template
+func documentTemplateName(tplid int) string {
+	return fmt.Sprintf("/GOFPDITPL%d", tplid)
+}
+
+// Write the Form XObjects of the templates and the objects they use, numbering them after the objects written so
+// far
+func (this *Document) putTemplates() error {
+	for _, reader := range this.readers {
+		writer := this.writers[reader]
+		writer.SetNextObjectID(this.n + 1)
+
+		names, err := writer.PutFormXobjects(reader)
+		if err != nil {
+			return errors.Wrap(err, "Failed to put form xobjects")
+		}
+
+		for _, tpl := range this.templates {
+			if tpl.writer != writer {
+				continue
+			}
+			if objId, ok := names[fmt.Sprintf("/GOFPDITPL%d", tpl.id)]; ok {
+				tpl.objId = objId.id
+			}
+		}
+
+		this.putWriterObjects(writer)
+
+		if err := this.flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write the objects written by a writer since the last call, in the order of their ids.  They end with endobj.
+func (this *Document) putWriterObjects(writer *PdfWriter) {
+	objects := writer.GetImportedObjects()
+	ids := make([]*PdfObjectId, 0, len(objects))
+	for id := range objects {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].id < ids[j].id
+	})
+
+	for _, id := range ids {
+		this.offsets[id.id] = this.offset + this.buf.Len()
+		this.buf.WriteString(fmt.Sprintf("%d 0 obj\n", id.id))
+		this.buf.Write(objects[id])
+	}
+	if this.n < writer.n {
+		this.n = writer.n
+	}
+
+	writer.ClearImportedObjects()
+}
+
+// Write a page and its content stream, and return a reference to the page
+func (this *Document) putPage(writer *PdfWriter, page *documentPage) (*PdfValue, error) {
+	var content bytes.Buffer
+	zw := zlib.NewWriter(&content)
+	if _, err := zw.Write(page.content.Bytes()); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress page content")
+	}
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress page content")
+	}
+
+	this.n++
+	contentId := this.n
+	this.putObject(writer, contentId, &PdfValue{
+		Type: PDF_TYPE_STREAM,
+		Value: &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+			"/Filter": {Type: PDF_TYPE_TOKEN, Token: "/FlateDecode"},
+		}},
+		Stream: &PdfValue{Type: PDF_TYPE_STREAM, Bytes: content.Bytes()},
+	})
+
+	xobjects := make(map[string]*PdfValue, len(page.templates))
+	for tplid := range page.templates {
+		xobjects[documentTemplateName(tplid)] = &PdfValue{Type: PDF_TYPE_OBJREF, Id: this.templates[tplid].objId}
+	}
+
+	resources := map[string]*PdfValue{
+		"/ProcSet": {Type: PDF_TYPE_ARRAY, Array: []*PdfValue{
+			{Type: PDF_TYPE_TOKEN, Token: "/PDF"},
+			{Type: PDF_TYPE_TOKEN, Token: "/Text"},
+			{Type: PDF_TYPE_TOKEN, Token: "/ImageB"},
+			{Type: PDF_TYPE_TOKEN, Token: "/ImageC"},
+			{Type: PDF_TYPE_TOKEN, Token: "/ImageI"},
+		}},
+	}
+	if len(xobjects) > 0 {
+		resources["/XObject"] = &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: xobjects}
+	}
+
+	this.n++
+	pageId := this.n
+	this.putObject(writer, pageId, &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+		"/Type":   {Type: PDF_TYPE_TOKEN, Token: "/Page"},
+		"/Parent": {Type: PDF_TYPE_OBJREF, Id: this.pagesId},
+		"/MediaBox": {Type: PDF_TYPE_ARRAY, Array: []*PdfValue{
+			{Type: PDF_TYPE_NUMERIC, Int: 0},
+			{Type: PDF_TYPE_NUMERIC, Int: 0},
+			{Type: PDF_TYPE_REAL, Real: page.width},
+			{Type: PDF_TYPE_REAL, Real: page.height},
+		}},
+		"/Resources": {Type: PDF_TYPE_DICTIONARY, Dictionary: resources},
+		"/Contents":  {Type: PDF_TYPE_OBJREF, Id: contentId},
+	}})
+
+	return &PdfValue{Type: PDF_TYPE_OBJREF, Id: pageId}, nil
+}
+
+// Write an indirect object
+func (this *Document) putObject(writer *PdfWriter, id int, value *PdfValue) {
+	this.offsets[id] = this.offset + this.buf.Len()
+
+	writer.out(fmt.Sprintf("%d 0 obj", id))
+	writer.writeValue(value)
+	writer.out("")
+	writer.out("endobj")
+}
+
+// Write the cross-reference table and the trailer
+func (this *Document) putXref(writer *PdfWriter, catalogId int) {
+	xrefPos := this.offset + this.buf.Len()
+
+	writer.out("xref")
+	writer.out(fmt.Sprintf("0 %d", this.n+1))
+	writer.straightOut("0000000000 65535 f\r\n")
+	for id := 1; id <= this.n; id++ {
+		if offset, ok := this.offsets[id]; ok {
+			writer.straightOut(fmt.Sprintf("%010d 00000 n\r\n", offset))
+		} else {
+			writer.straightOut("0000000000 00000 f\r\n")
+		}
+	}
+
+	writer.out("trailer")
+	writer.writeValue(&PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+		"/Size": {Type: PDF_TYPE_NUMERIC, Int: this.n + 1},
+		"/Root": {Type: PDF_TYPE_OBJREF, Id: catalogId},
+	}})
+	writer.out("")
+	writer.out("startxref")
+	writer.out(fmt.Sprintf("%d", xrefPos))
+	writer.out("%%EOF")
+}
+
+// Write the buffered output
+func (this *Document) flush() error {
+	n, err := this.w.Write(this.buf.Bytes())
+	this.offset += n
+	this.buf.Reset()
+	if err != nil {
+		return errors.Wrap(err, "Failed to write document")
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package gofpdi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	source := readTestFile(t, "simple.pdf")
+
+	var buf bytes.Buffer
+	doc := NewDocument(&buf)
+
+	first, err := doc.ImportPage(source, 1, "/MediaBox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := doc.ImportPage(source, 3, "/MediaBox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, h, err := doc.TemplateSize(first)
+	if err != nil || w != 612 || h != 792 {
+		t.Errorf("template size %v x %v, %v, want 612 x 792", w, h, err)
+	}
+
+	if err := doc.UseTemplate(first, 0, 0, 0, 0); err == nil {
+		t.Error("template used without a page")
+	}
+
+	// A page of the imported size, and a landscape page with both pages side by side
+	if err := doc.AddPage(w, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.UseTemplate(first, 0, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AddPage(2*h, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.UseTemplate(third, 0, 0, 0, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.UseTemplate(first, h, 0, 0, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AddContent("0 0 1 RG 0 0 m 10 10 l S"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doc.UseTemplate(2, 0, 0, 0, 0); err == nil {
+		t.Error("unknown template used")
+	}
+	if err := doc.AddPage(0, h); err == nil {
+		t.Error("page without width added")
+	}
+	if doc.PageCount() != 2 {
+		t.Errorf("%d pages, want 2", doc.PageCount())
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes written before the document was closed", buf.Len())
+	}
+	if err := doc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Close(); err == nil {
+		t.Error("document closed twice")
+	}
+	if err := doc.AddPage(w, h); err == nil {
+		t.Error("page added to a closed document")
+	}
+
+	reader, err := NewPdfReaderFromStream(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read the document: %v", err)
+	}
+	if reader.NumPages() != 2 {
+		t.Fatalf("%d pages, want 2", reader.NumPages())
+	}
+
+	for i, want := range []PageBox{{0, 0, 612, 792}, {0, 0, 1584, 792}} {
+		boxes, err := reader.PageBoxes(i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if boxes.MediaBox != want {
+			t.Errorf("page %d media box %v, want %v", i+1, boxes.MediaBox, want)
+		}
+	}
+
+	// The template of the first page is written once, and used by both pages
+	resources, err := reader.getPageResources(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xobjects, err := reader.Resolve(resources.Key("/XObject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xobjects.Keys()) != 2 {
+		t.Errorf("page 2 uses XObjects %v, want 2", xobjects.Keys())
+	}
+
+	content, err := reader.getContent(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "0 0 1 RG 0 0 m 10 10 l S") {
+		t.Errorf("page 2 content %q", content)
+	}
+}
+
+func TestEmptyDocument(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewDocument(&buf).Close(); err == nil {
+		t.Error("document without pages closed")
+	}
+}
+
+func TestCopyPages(t *testing.T) {
+	source := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm << /Fields [9 0 R] >> >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 3 /MediaBox [0 0 612 792] >>",
+		"<< /Type /Page /Parent 2 0 R /Annots [6 0 R 7 0 R 8 0 R] >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Annots [10 0 R] >>",
+		"<< /Subtype /Link /Rect [0 0 10 10] /Dest [4 0 R /Fit] >>",
+		"<< /Subtype /Link /Rect [0 0 10 10] /Dest [5 0 R /Fit] >>",
+		"<< /Subtype /Widget /Parent 9 0 R /P 3 0 R /Rect [0 0 10 10] >>",
+		"<< /FT /Tx /T (name) /Kids [8 0 R 10 0 R] >>",
+		"<< /Subtype /Widget /Parent 9 0 R /P 5 0 R /Rect [0 0 10 10] >>",
+	)
+
+	var buf bytes.Buffer
+	doc := NewDocument(&buf)
+	if err := doc.CopyPages(source, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewPdfReaderFromStream(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read the document: %v", err)
+	}
+	if reader.NumPages() != 2 {
+		t.Fatalf("%d pages, want 2", reader.NumPages())
+	}
+
+	page, err := reader.Page(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := reader.Page(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annots, err := reader.Resolve(page.Key("/Annots"))
+	if err != nil || annots.Len() != 3 {
+		t.Fatalf("page 1 annotations %v, %v", annots, err)
+	}
+
+	// The link to the copied page points to the copy, the one to the page that is not copied is null
+	link, err := reader.Resolve(annots.Index(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest := link.Key("/Dest").Index(0); !dest.IsRef() || dest.Id != second.Id {
+		t.Errorf("link destination %v, want page object %d", dest, second.Id)
+	}
+	link, err = reader.Resolve(annots.Index(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest := link.Key("/Dest").Index(0); !dest.IsNull() {
+		t.Errorf("link destination %v, want null", dest)
+	}
+
+	// The widget on the page that is not copied is replaced with null
+	fields, err := reader.FormFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || len(fields[0].Widgets) != 2 {
+		t.Fatalf("form fields %v, want a field with two kids", fields)
+	}
+	if widget := fields[0].Widgets[0]; widget.Page != 1 {
+		t.Errorf("widget on page %d, want 1", widget.Page)
+	}
+	if widget := fields[0].Widgets[1]; widget.id != 0 {
+		t.Errorf("widget of a page that is not copied is object %d, want null", widget.id)
+	}
+}
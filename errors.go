@@ -0,0 +1,176 @@
+package gofpdi
+
+import (
+	stderrors "errors"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/tim-timpani/gofpdi/text"
+)
+
+// Sentinel errors.  Errors returned by this package wrap them, so they can be checked with errors.Is
+// (from the standard library or github.com/pkg/errors).
+var (
+	// The document is encrypted and cannot be opened without a password
+	ErrEncrypted = stderrors.New("Document is encrypted, a password is required")
+
+	// The supplied password is neither the user nor the owner password of an encrypted document
+	ErrInvalidPassword = stderrors.New("Invalid password")
+
+	// The document is encrypted with a security handler or algorithm that is not supported
+	ErrUnsupportedEncryption = stderrors.New("Unsupported encryption")
+
+	// A stream uses a filter (e.g. /DCTDecode or /JBIG2Decode) that cannot be decoded
+	ErrUnsupportedFilter = stderrors.New("Unsupported filter")
+
+	// A page number is less than 1 or greater than the number of pages
+	ErrPageOutOfRange = stderrors.New("Page out of range")
+
+	// No page has the requested page label
+	ErrPageLabelNotFound = stderrors.New("Page label not found")
+
+	// The requested page box (and its fallbacks) is not defined for a page
+	ErrBoxNotFound = stderrors.New("Page box not found")
+
+	// The cross-reference table is missing or points to the wrong offsets.  Retrying with WithXrefRepair may help.
+	ErrCorruptXref = stderrors.New("Corrupt xref table")
+
+	// An object referenced by the document is not listed in the cross-reference table
+	ErrObjectNotFound = stderrors.New("Object not found")
+
+	// A form field with the given name does not exist (or is not a terminal field)
+	ErrFieldNotFound = stderrors.New("Form field not found")
+
+	// A value cannot be set on a form field, because of its type, options or maximum length
+	ErrInvalidFieldValue = stderrors.New("Invalid form field value")
+
+	// A font used by a page is not defined in its resources
+	ErrMissingFont = text.ErrMissingFont
+)
+
+// PageError is returned when an operation on a page fails
+type PageError struct {
+	Page int
+	Err  error
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("Page %d: %v", e.Page, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PageError) Cause() error {
+	return e.Err
+}
+
+// ObjectError is returned when an indirect object cannot be read.
+// Offset is the byte offset of the object in the file, or -1 if unknown or stored in an object stream.
+type ObjectError struct {
+	Id     int
+	Gen    int
+	Offset int64
+	Err    error
+}
+
+func (e *ObjectError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("Object %d %d R: %v", e.Id, e.Gen, e.Err)
+	}
+	return fmt.Sprintf("Object %d %d R at offset %d: %v", e.Id, e.Gen, e.Offset, e.Err)
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ObjectError) Cause() error {
+	return e.Err
+}
+
+// FilterError is returned when stream data cannot be decoded with one of its filters
+type FilterError struct {
+	Filter string
+	Err    error
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("Filter %s: %v", e.Filter, e.Err)
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FilterError) Cause() error {
+	return e.Err
+}
+
+// XrefError is returned when the cross-reference table cannot be read, or when it points to the wrong
+// place in the file.  Offset is the byte offset of the xref section or object involved, or -1 if unknown.
+// It always matches ErrCorruptXref.
+type XrefError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *XrefError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("Corrupt xref: %v", e.Err)
+	}
+	return fmt.Sprintf("Corrupt xref at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *XrefError) Unwrap() error {
+	return e.Err
+}
+
+func (e *XrefError) Cause() error {
+	return e.Err
+}
+
+func (e *XrefError) Is(target error) bool {
+	return target == ErrCorruptXref
+}
+
+// FontError is returned when a font resource of a page cannot be loaded.  It matches ErrMissingFont when the font
+// object does not exist.
+type FontError struct {
+	Font string
+	Err  error
+}
+
+func (e *FontError) Error() string {
+	return fmt.Sprintf("Font %s: %v", e.Font, e.Err)
+}
+
+func (e *FontError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FontError) Cause() error {
+	return e.Err
+}
+
+func (e *FontError) Is(target error) bool {
+	return target == ErrMissingFont && errors.Is(e.Err, ErrObjectNotFound)
+}
+
+// Check that a page number is within the document, returning a *PageError otherwise
+func (this *PdfReader) checkPage(pageno int) error {
+	if pageno < 1 || pageno > this.numPages() {
+		return &PageError{Page: pageno, Err: ErrPageOutOfRange}
+	}
+	return nil
+}
+
+// Wrap an error in a *PageError, unless it already is one
+func pageError(pageno int, err error) error {
+	var pageErr *PageError
+	if errors.As(err, &pageErr) {
+		return err
+	}
+	return &PageError{Page: pageno, Err: err}
+}
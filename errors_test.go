@@ -0,0 +1,76 @@
+package gofpdi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestObjectAndPageErrors(t *testing.T) {
+	reader := readTestFile(t, "simple.pdf")
+
+	_, err := reader.Object(99, 0)
+	var objErr *ObjectError
+	if !errors.As(err, &objErr) || objErr.Id != 99 || !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Object(99, 0) = %v, want an ObjectError matching ErrObjectNotFound", err)
+	}
+
+	_, err = reader.Page(4)
+	var pageErr *PageError
+	if !errors.As(err, &pageErr) || pageErr.Page != 4 || !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("Page(4) = %v, want a PageError matching ErrPageOutOfRange", err)
+	}
+}
+
+func TestFilterError(t *testing.T) {
+	data := buildTestPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Filter [/ASCIIHexDecode /JBIG2Decode] /Length 5 >>\nstream\n4142>\nendstream",
+	)
+
+	reader, err := NewPdfReaderFromStream(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := reader.Object(3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = reader.DecodeStream(stream)
+	var filterErr *FilterError
+	if !errors.As(err, &filterErr) || filterErr.Filter != "/JBIG2Decode" || !errors.Is(err, ErrUnsupportedFilter) {
+		t.Errorf("DecodeStream = %v, want a FilterError matching ErrUnsupportedFilter", err)
+	}
+}
+
+func TestFontError(t *testing.T) {
+	data := buildTestPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 9 0 R >> >> /Contents 4 0 R >>",
+		"<< /Length 28 >>\nstream\nBT /F1 12 Tf (Missing) Tj ET\nendstream",
+	)
+
+	reader, err := NewPdfReaderFromStream(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter, err := NewExporterFromReader(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = exporter.GetPagePlainText(1)
+	var fontErr *FontError
+	if !errors.As(err, &fontErr) || !errors.Is(err, ErrMissingFont) {
+		t.Errorf("GetPagePlainText = %v, want a FontError matching ErrMissingFont", err)
+	}
+
+	// Fonts that exist but cannot be loaded are not missing
+	fontErr = &FontError{Font: "/F1", Err: errors.New("Invalid font")}
+	if errors.Is(fontErr, ErrMissingFont) {
+		t.Error("FontError of an existing font matches ErrMissingFont")
+	}
+}
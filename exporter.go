@@ -16,12 +16,12 @@ type Exporter struct {
 	reader         *PdfReader
 }
 
-func NewExporter(sourceFileName string) (*Exporter, error) {
-	reader, err := NewPdfReader(sourceFileName)
+func NewExporter(sourceFileName string, opts ...ReaderOption) (*Exporter, error) {
+	reader, err := NewPdfReader(sourceFileName, opts...)
 	if err != nil {
 		return nil, err
 	}
-	if reader.pageCount < 1 {
+	if reader.numPages() < 1 {
 		return nil, fmt.Errorf("file '%s' has no pages", sourceFileName)
 	}
 	return &Exporter{
@@ -30,24 +30,61 @@ func NewExporter(sourceFileName string) (*Exporter, error) {
 	}, nil
 }
 
+// NewExporterFromReader creates an exporter for an already parsed document (e.g. one shared with an Importer).
+// An exporter may be used from several goroutines to extract the text of pages in parallel.
+func NewExporterFromReader(reader *PdfReader) (*Exporter, error) {
+	if reader.numPages() < 1 {
+		return nil, fmt.Errorf("file '%s' has no pages", reader.sourceFile)
+	}
+	return &Exporter{
+		sourceFileName: reader.sourceFile,
+		reader:         reader,
+	}, nil
+}
+
 // GetPagePlainText returns the plain text from a given page.  Page numbers start with 1 in the PDF world
 func (e *Exporter) GetPagePlainText(pageNumber int) (string, error) {
 	_, text, err := e.getTextShowOperations(pageNumber)
-	return text, err
+	if err != nil {
+		return "", pageError(pageNumber, err)
+	}
+	return text, nil
 }
 
 func (e *Exporter) ExportToPlainTextFile(fileName string) error {
+	return e.exportToPlainTextFile(fileName, false)
+}
+
+// ExportToPlainTextFileWithLabels is the same as ExportToPlainTextFile, but starts the text of each page with a
+// line holding its page label (e.g. "--- Page iv ---")
+func (e *Exporter) ExportToPlainTextFileWithLabels(fileName string) error {
+	return e.exportToPlainTextFile(fileName, true)
+}
+
+func (e *Exporter) exportToPlainTextFile(fileName string, withLabels bool) error {
+	var labels []pageLabelRange
+	if withLabels {
+		var err error
+		labels, err = e.reader.pageLabelRanges()
+		if err != nil {
+			return err
+		}
+	}
+
 	outFile, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
 	defer outFile.Close()
 	outBuffer := io.StringWriter(outFile)
-	for pageNumber := 1; pageNumber <= e.reader.pageCount; pageNumber++ {
+	for pageNumber := 1; pageNumber <= e.reader.numPages(); pageNumber++ {
 		pageText, err := e.GetPagePlainText(pageNumber)
 		if err != nil {
 			return err
 		}
+		if withLabels {
+			outBuffer.WriteString(fmt.Sprintf("--- Page %s ---\n", pageLabel(labels, pageNumber-1)))
+		}
 		outBuffer.WriteString(pageText)
 	}
 	return nil
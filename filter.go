@@ -0,0 +1,449 @@
+package gofpdi
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Decode the data of a stream object by applying all of its /Filter entries in order.
+// The /DecodeParms entry (a dictionary or an array of dictionaries) supplies the parameters for each filter.
+func (this *PdfReader) decodeStream(obj *PdfValue) ([]byte, error) {
+	if obj.Stream == nil {
+		return nil, errors.New("Object is not a stream")
+	}
+
+	filters, parms, err := this.streamFilters(obj.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	data := obj.Stream.Bytes
+
+	for i := 0; i < len(filters); i++ {
+		data, err = decodeFilter(filters[i], data, parms[i])
+		if err != nil {
+			return nil, &FilterError{Filter: filters[i], Err: err}
+		}
+	}
+
+	return data, nil
+}
+
+// Get the filter names of a stream dictionary along with the decode parameters of each filter.
+// A nil entry in the parameter slice means the filter uses its default parameters.
+func (this *PdfReader) streamFilters(dict *PdfValue) ([]string, []*PdfValue, error) {
+	filters := make([]string, 0)
+	parms := make([]*PdfValue, 0)
+
+	filter, ok := dict.Dictionary["/Filter"]
+	if !ok {
+		return filters, parms, nil
+	}
+
+	filter, err := this.resolveDirect(filter)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to resolve /Filter")
+	}
+
+	if filter.Type == PDF_TYPE_TOKEN {
+		filters = append(filters, filter.Token)
+	} else if filter.Type == PDF_TYPE_ARRAY {
+		for i := 0; i < len(filter.Array); i++ {
+			f, err := this.resolveDirect(filter.Array[i])
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "Failed to resolve /Filter")
+			}
+			filters = append(filters, f.Token)
+		}
+	}
+
+	parms = make([]*PdfValue, len(filters))
+
+	decodeParms, ok := dict.Dictionary["/DecodeParms"]
+	if !ok {
+		// /DP is the abbreviation used by inline images
+		decodeParms, ok = dict.Dictionary["/DP"]
+	}
+	if !ok {
+		return filters, parms, nil
+	}
+
+	decodeParms, err = this.resolveDirect(decodeParms)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to resolve /DecodeParms")
+	}
+
+	if decodeParms.Type == PDF_TYPE_DICTIONARY && len(filters) > 0 {
+		parms[0] = decodeParms
+	} else if decodeParms.Type == PDF_TYPE_ARRAY {
+		for i := 0; i < len(decodeParms.Array) && i < len(filters); i++ {
+			p, err := this.resolveDirect(decodeParms.Array[i])
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "Failed to resolve /DecodeParms")
+			}
+			if p.Type == PDF_TYPE_DICTIONARY {
+				parms[i] = p
+			}
+		}
+	}
+
+	return filters, parms, nil
+}
+
+// Resolve a value if it is an indirect reference and return the direct value
+func (this *PdfReader) resolveDirect(value *PdfValue) (*PdfValue, error) {
+	if value.Type != PDF_TYPE_OBJREF {
+		return value, nil
+	}
+
+	res, err := this.resolveObject(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Value, nil
+}
+
+// Get an integer decode parameter, or a default value if it is not set
+func decodeParm(parms *PdfValue, key string, def int) int {
+	if parms == nil {
+		return def
+	}
+	if v, ok := parms.Dictionary[key]; ok && v.Type == PDF_TYPE_NUMERIC {
+		return v.Int
+	}
+	return def
+}
+
+// Apply a single filter to data
+func decodeFilter(filter string, data []byte, parms *PdfValue) ([]byte, error) {
+	switch filter {
+	case "/FlateDecode", "/Fl":
+		out, err := flateDecode(data)
+		if err != nil {
+			return nil, err
+		}
+		return applyPredictor(out, parms)
+
+	case "/LZWDecode", "/LZW":
+		out, err := lzwDecode(data, decodeParm(parms, "/EarlyChange", 1))
+		if err != nil {
+			return nil, err
+		}
+		return applyPredictor(out, parms)
+
+	case "/ASCIIHexDecode", "/AHx":
+		return asciiHexDecode(data), nil
+
+	case "/ASCII85Decode", "/A85":
+		return ascii85Decode(data)
+
+	case "/RunLengthDecode", "/RL":
+		return runLengthDecode(data), nil
+
+	case "/Crypt":
+		// Only the /Identity crypt filter is supported, which leaves the data unchanged
+		if parms != nil {
+			if name, ok := parms.Dictionary["/Name"]; ok && name.Token != "/Identity" {
+				return nil, errors.Wrap(ErrUnsupportedFilter, "Crypt filter "+name.Token)
+			}
+		}
+		return data, nil
+	}
+
+	return nil, ErrUnsupportedFilter
+}
+
+// Decompress zlib (or raw deflate) data.
+// Data that is truncated or corrupt at the end is returned as far as it could be decompressed.
+func flateDecode(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Some producers omit the zlib header
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil && len(out) == 0 {
+		return nil, errors.Wrap(err, "Failed to decompress data")
+	}
+
+	return out, nil
+}
+
+// Decode hex encoded data terminated by '>'
+func asciiHexDecode(data []byte) []byte {
+	if i := bytes.IndexByte(data, '>'); i >= 0 {
+		data = data[:i]
+	}
+
+	return decodeHexString(string(data))
+}
+
+// Decode ASCII base-85 encoded data terminated by '~>'
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+
+	// 'z' stands for four zero bytes, so the output may be four times as long as the input
+	out := make([]byte, 4*len(data)+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode ASCII85 data")
+	}
+
+	return out[:n], nil
+}
+
+// Decode run-length encoded data
+func runLengthDecode(data []byte) []byte {
+	var out bytes.Buffer
+
+	for i := 0; i < len(data); {
+		length := int(data[i])
+		i++
+
+		if length == 128 {
+			// End of data
+			break
+		}
+
+		if length < 128 {
+			// Copy the next length + 1 bytes literally
+			end := i + length + 1
+			if end > len(data) {
+				end = len(data)
+			}
+			out.Write(data[i:end])
+			i = end
+		} else if i < len(data) {
+			// Repeat the next byte 257 - length times
+			out.Write(bytes.Repeat(data[i:i+1], 257-length))
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
+
+// Decode LZW compressed data.
+// earlyChange is the /EarlyChange parameter: 1 (the default) increases the code width one code early.
+func lzwDecode(data []byte, earlyChange int) ([]byte, error) {
+	const (
+		clearCode = 256
+		eodCode   = 257
+		maxCodes  = 4096
+	)
+
+	var out bytes.Buffer
+
+	table := make([][]byte, maxCodes)
+	for i := 0; i < 256; i++ {
+		table[i] = []byte{byte(i)}
+	}
+
+	nextCode := 258
+	width := 9
+	var prev []byte
+
+	// Bit reader state
+	var bits uint32
+	var nBits uint
+	pos := 0
+
+	for {
+		// Fill the bit buffer with enough bits for the next code
+		for nBits < uint(width) && pos < len(data) {
+			bits = bits<<8 | uint32(data[pos])
+			nBits += 8
+			pos++
+		}
+		if nBits < uint(width) {
+			break
+		}
+
+		code := int(bits>>(nBits-uint(width))) & (1<<uint(width) - 1)
+		nBits -= uint(width)
+
+		if code == clearCode {
+			nextCode = 258
+			width = 9
+			prev = nil
+			continue
+		}
+
+		if code == eodCode {
+			break
+		}
+
+		var entry []byte
+		if code < nextCode && table[code] != nil {
+			entry = table[code]
+		} else if code == nextCode && prev != nil {
+			entry = append(append([]byte{}, prev...), prev[0])
+		} else {
+			return nil, errors.New(fmt.Sprintf("Invalid LZW code: %d", code))
+		}
+
+		out.Write(entry)
+
+		if prev != nil && nextCode < maxCodes {
+			table[nextCode] = append(append([]byte{}, prev...), entry[0])
+			nextCode++
+		}
+
+		if nextCode+earlyChange >= 1<<uint(width) && width < 12 {
+			width++
+		}
+
+		prev = entry
+	}
+
+	return out.Bytes(), nil
+}
+
+// Reverse the TIFF or PNG predictor given by the /Predictor decode parameter of a Flate or LZW stream
+func applyPredictor(data []byte, parms *PdfValue) ([]byte, error) {
+	predictor := decodeParm(parms, "/Predictor", 1)
+	if predictor <= 1 {
+		return data, nil
+	}
+
+	colors := decodeParm(parms, "/Colors", 1)
+	bpc := decodeParm(parms, "/BitsPerComponent", 8)
+	columns := decodeParm(parms, "/Columns", 1)
+
+	if colors < 1 || columns < 1 {
+		return nil, errors.New(fmt.Sprintf("Invalid predictor parameters: /Colors %d /Columns %d", colors, columns))
+	}
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, errors.New(fmt.Sprintf("Invalid predictor parameter: /BitsPerComponent %d", bpc))
+	}
+
+	// Number of bytes in a row of pixels and in a complete pixel (at least 1)
+	rowLength := (colors*bpc*columns + 7) / 8
+	bytesPerPixel := (colors*bpc + 7) / 8
+
+	if predictor == 2 {
+		return tiffPredictor(data, rowLength, colors, bpc, columns), nil
+	}
+	if predictor >= 10 {
+		return pngPredictor(data, rowLength, bytesPerPixel)
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unsupported predictor: %d", predictor))
+}
+
+// Reverse PNG prediction.  Each row is prefixed by a byte selecting the filter used for that row.
+func pngPredictor(data []byte, rowLength, bytesPerPixel int) ([]byte, error) {
+	out := make([]byte, 0, len(data)/(rowLength+1)*rowLength)
+	prev := make([]byte, rowLength)
+
+	for pos := 0; pos < len(data); pos += rowLength + 1 {
+		end := pos + rowLength + 1
+		if end > len(data) {
+			// Tolerate a truncated final row
+			end = len(data)
+		}
+
+		filterType := data[pos]
+		row := make([]byte, rowLength)
+		copy(row, data[pos+1:end])
+
+		switch filterType {
+		case 0:
+			// None
+		case 1:
+			// Sub
+			for i := bytesPerPixel; i < rowLength; i++ {
+				row[i] += row[i-bytesPerPixel]
+			}
+		case 2:
+			// Up
+			for i := 0; i < rowLength; i++ {
+				row[i] += prev[i]
+			}
+		case 3:
+			// Average
+			for i := 0; i < rowLength; i++ {
+				left := 0
+				if i >= bytesPerPixel {
+					left = int(row[i-bytesPerPixel])
+				}
+				row[i] += byte((left + int(prev[i])) / 2)
+			}
+		case 4:
+			// Paeth
+			filterPaeth(row, prev, bytesPerPixel)
+		default:
+			return nil, errors.New(fmt.Sprintf("Invalid PNG predictor filter type: %d", filterType))
+		}
+
+		out = append(out, row[:end-pos-1]...)
+		prev = row
+	}
+
+	return out, nil
+}
+
+// Reverse TIFF predictor 2, where each color component is stored as the difference to the same
+// component of the pixel on its left
+func tiffPredictor(data []byte, rowLength, colors, bpc, columns int) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	for pos := 0; pos+rowLength <= len(out); pos += rowLength {
+		row := out[pos : pos+rowLength]
+
+		switch bpc {
+		case 8:
+			for i := colors; i < rowLength; i++ {
+				row[i] += row[i-colors]
+			}
+
+		case 16:
+			for i := 2 * colors; i+1 < rowLength; i += 2 {
+				v := (int(row[i])<<8 | int(row[i+1])) + (int(row[i-2*colors])<<8 | int(row[i-2*colors+1]))
+				row[i] = byte(v >> 8)
+				row[i+1] = byte(v)
+			}
+
+		default:
+			// Components smaller than a byte are unpacked, summed and packed again
+			mask := 1<<uint(bpc) - 1
+			count := colors * columns
+			components := make([]int, count)
+			for i := 0; i < count; i++ {
+				shift := uint(8 - bpc - (i*bpc)%8)
+				components[i] = int(row[i*bpc/8]>>shift) & mask
+			}
+			for i := colors; i < count; i++ {
+				components[i] = (components[i] + components[i-colors]) & mask
+			}
+			for i := range row {
+				row[i] = 0
+			}
+			for i := 0; i < count; i++ {
+				shift := uint(8 - bpc - (i*bpc)%8)
+				row[i*bpc/8] |= byte(components[i] << shift)
+			}
+		}
+	}
+
+	return out
+}
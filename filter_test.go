@@ -0,0 +1,99 @@
+package gofpdi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestASCII85Decode(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"87cURD_*#4DfTZ)+T~>", "Hello, World!"},
+		{"<~87cURD_*#4DfTZ)+T~>", "Hello, World!"},
+		{"87cUR\nD_*#4 DfTZ)+T~>", "Hello, World!"},
+		{"z~>", strings.Repeat("\x00", 4)},
+		{"zzzzzzzzzz~>", strings.Repeat("\x00", 40)},
+		{"z87cURz~>", "\x00\x00\x00\x00Hell\x00\x00\x00\x00"},
+		{"~>", ""},
+	}
+
+	for _, test := range tests {
+		got, err := ascii85Decode([]byte(test.data))
+		if err != nil {
+			t.Errorf("ascii85Decode(%q): %v", test.data, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("ascii85Decode(%q) = %q, want %q", test.data, got, test.want)
+		}
+	}
+
+	if _, err := ascii85Decode([]byte("87c{URD~>")); err == nil {
+		t.Error("ascii85Decode accepted an invalid character")
+	}
+}
+
+func TestASCIIHexDecode(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"48656C6C6F>", "Hello"},
+		{"48 65 6c\n6c 6f>", "Hello"},
+		{"48656C6C6>", "Hell`"},
+	}
+
+	for _, test := range tests {
+		if got := asciiHexDecode([]byte(test.data)); string(got) != test.want {
+			t.Errorf("asciiHexDecode(%q) = %q, want %q", test.data, got, test.want)
+		}
+	}
+}
+
+func TestRunLengthDecode(t *testing.T) {
+	// A literal run of 3 bytes, a repeated byte and the end of data marker
+	data := []byte{2, 'a', 'b', 'c', 253, 'x', 128, 'z'}
+	if got := runLengthDecode(data); string(got) != "abcxxxx" {
+		t.Errorf("runLengthDecode = %q, want %q", got, "abcxxxx")
+	}
+}
+
+func TestApplyPredictor(t *testing.T) {
+	parms := func(predictor, colors, columns int) *PdfValue {
+		return &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+			"/Predictor": {Type: PDF_TYPE_NUMERIC, Int: predictor},
+			"/Colors":    {Type: PDF_TYPE_NUMERIC, Int: colors},
+			"/Columns":   {Type: PDF_TYPE_NUMERIC, Int: columns},
+		}}
+	}
+
+	tests := []struct {
+		name  string
+		parms *PdfValue
+		data  []byte
+		want  []byte
+	}{
+		{"none", nil, []byte{1, 2, 3}, []byte{1, 2, 3}},
+		{"png none", parms(10, 1, 3), []byte{0, 1, 2, 3, 0, 4, 5, 6}, []byte{1, 2, 3, 4, 5, 6}},
+		{"png sub", parms(11, 1, 3), []byte{1, 1, 1, 1, 1, 5, 250, 10}, []byte{1, 2, 3, 5, 255, 9}},
+		{"png up", parms(12, 1, 3), []byte{2, 1, 2, 3, 2, 1, 1, 1}, []byte{1, 2, 3, 2, 3, 4}},
+		{"png average", parms(13, 1, 2), []byte{3, 4, 4, 3, 2, 2}, []byte{4, 6, 4, 7}},
+		{"png paeth", parms(14, 1, 2), []byte{4, 4, 4, 4, 1, 1}, []byte{4, 8, 5, 9}},
+		{"png rgb sub", parms(11, 3, 2), []byte{1, 10, 20, 30, 1, 2, 3}, []byte{10, 20, 30, 11, 22, 33}},
+		{"tiff", parms(2, 1, 3), []byte{1, 1, 1, 5, 5, 5}, []byte{1, 2, 3, 5, 10, 15}},
+	}
+
+	for _, test := range tests {
+		got, err := applyPredictor(test.data, test.parms)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("%s: applyPredictor = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package gofpdi
+
+import (
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// FormFiller sets the values of form fields and saves them as an incremental update of the document, so the
+// original file (and any signature covering it) stays intact.  Fields are addressed by their fully qualified name.
+//
+// The appearances of text fields, combo boxes, checkboxes and radio buttons are generated for the new values, so
+// they show up in viewers that do not regenerate them and in pages imported as templates.  Text is laid out with
+// the font of the field's default appearance (/DA) if it is a simple font with WinAnsiEncoding, and with Helvetica
+// otherwise.  Rotated widgets (/MK /R) get rotated appearances.
+type FormFiller struct {
+	reader   *PdfReader
+	update   *IncrementalUpdate
+	fields   map[string]*FormField
+	acroForm *PdfValue
+
+	// Fonts used by generated appearances, by resource name, and standard fonts added to the update, by name
+	fonts         map[string]*appearanceFont
+	standardFonts map[string]*PdfValue
+}
+
+// NewFormFiller creates a form filler for the document read by reader
+func NewFormFiller(reader *PdfReader) (*FormFiller, error) {
+	update, err := NewIncrementalUpdate(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := reader.TerminalFormFields()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read form fields")
+	}
+
+	filler := &FormFiller{
+		reader:        reader,
+		update:        update,
+		fields:        fields,
+		fonts:         make(map[string]*appearanceFont, 0),
+		standardFonts: make(map[string]*PdfValue, 0),
+	}
+
+	if acroFormSpec := reader.catalog.Key("/AcroForm"); acroFormSpec != nil {
+		filler.acroForm, err = reader.Resolve(acroFormSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve AcroForm")
+		}
+	}
+
+	return filler, nil
+}
+
+// Fields returns the terminal fields of the form, keyed by their fully qualified name.  Their values reflect the
+// changes made so far.
+func (this *FormFiller) Fields() map[string]*FormField {
+	return this.fields
+}
+
+// SetText sets the value of a text field
+func (this *FormFiller) SetText(name, value string) error {
+	field, err := this.field(name, FormFieldText)
+	if err != nil {
+		return err
+	}
+
+	if field.MaxLen > 0 && utf8.RuneCountInString(value) > field.MaxLen {
+		return errors.Wrapf(ErrInvalidFieldValue, "Value of field %s is longer than %d characters", name, field.MaxLen)
+	}
+
+	dict, err := this.editField(field)
+	if err != nil {
+		return err
+	}
+	dict["/V"] = newTextStringValue(value)
+
+	field.Value = value
+	field.Values = []string{value}
+
+	return this.setTextAppearance(field, value)
+}
+
+// SetCheckbox checks or unchecks a checkbox
+func (this *FormFiller) SetCheckbox(name string, checked bool) error {
+	field, err := this.field(name, FormFieldCheckbox)
+	if err != nil {
+		return err
+	}
+
+	state := "Off"
+	if checked {
+		state = "Yes"
+		if len(field.States) > 0 {
+			state = field.States[0]
+		}
+	}
+
+	return this.setButtonState(field, state)
+}
+
+// SetRadio selects the button of a radio button group with the given state (one of the field's States), or
+// deselects all buttons if state is "Off" or empty
+func (this *FormFiller) SetRadio(name, state string) error {
+	field, err := this.field(name, FormFieldRadio)
+	if err != nil {
+		return err
+	}
+
+	if state == "" {
+		state = "Off"
+	}
+	if state != "Off" && !in_array(state, field.States) {
+		return errors.Wrapf(ErrInvalidFieldValue, "Field %s has no state %s", name, state)
+	}
+
+	return this.setButtonState(field, state)
+}
+
+// SetChoice selects options of a list box or combo box by their export values.  Selecting more than one option
+// requires a multiple selection field; editable combo boxes also accept values that are not among the options.
+func (this *FormFiller) SetChoice(name string, values ...string) error {
+	field, err := this.field(name, FormFieldChoice)
+	if err != nil {
+		return err
+	}
+
+	if len(values) > 1 && field.Flags&FieldFlagMultiSelect == 0 {
+		return errors.Wrapf(ErrInvalidFieldValue, "Field %s does not allow multiple selections", name)
+	}
+
+	editable := field.Flags&FieldFlagCombo != 0 && field.Flags&FieldFlagEdit != 0
+	indexes := make([]*PdfValue, 0, len(values))
+	for _, value := range values {
+		index := -1
+		for i, option := range field.Options {
+			if option.Export == value {
+				index = i
+				break
+			}
+		}
+		if index < 0 && len(field.Options) > 0 && !editable {
+			return errors.Wrapf(ErrInvalidFieldValue, "Field %s has no option %s", name, value)
+		}
+		if index >= 0 {
+			indexes = append(indexes, &PdfValue{Type: PDF_TYPE_NUMERIC, Int: index})
+		}
+	}
+
+	dict, err := this.editField(field)
+	if err != nil {
+		return err
+	}
+
+	switch len(values) {
+	case 0:
+		delete(dict, "/V")
+	case 1:
+		dict["/V"] = newTextStringValue(values[0])
+	default:
+		array := make([]*PdfValue, 0, len(values))
+		for _, value := range values {
+			array = append(array, newTextStringValue(value))
+		}
+		dict["/V"] = &PdfValue{Type: PDF_TYPE_ARRAY, Array: array}
+	}
+
+	// The selected indexes are only needed to tell apart options with the same export value of multiple
+	// selection fields, and would be stale otherwise
+	if len(indexes) > 1 {
+		dict["/I"] = &PdfValue{Type: PDF_TYPE_ARRAY, Array: indexes}
+	} else {
+		delete(dict, "/I")
+	}
+
+	field.Value = ""
+	if len(values) > 0 {
+		field.Value = values[0]
+	}
+	field.Values = values
+
+	// Appearances of list boxes are left to the viewer
+	if field.Flags&FieldFlagCombo == 0 {
+		return this.setNeedAppearances()
+	}
+
+	display := field.Value
+	for _, option := range field.Options {
+		if option.Export == field.Value {
+			display = option.Display
+			break
+		}
+	}
+
+	return this.setTextAppearance(field, display)
+}
+
+// Write writes the original document followed by an incremental update holding the changed fields
+func (this *FormFiller) Write(w io.Writer) error {
+	return this.update.Write(w)
+}
+
+// WriteUpdate writes only the incremental update, which must be appended to the original document
+func (this *FormFiller) WriteUpdate(w io.Writer) error {
+	return this.update.WriteUpdate(w)
+}
+
+// Look up a terminal field and check its type
+func (this *FormFiller) field(name, fieldType string) (*FormField, error) {
+	field, ok := this.fields[name]
+	if !ok {
+		return nil, errors.Wrap(ErrFieldNotFound, name)
+	}
+
+	if field.Type != fieldType {
+		return nil, errors.Wrapf(ErrInvalidFieldValue, "Field %s is a %s field, not a %s field", name, field.Type, fieldType)
+	}
+
+	return field, nil
+}
+
+// Set the value of a checkbox or radio button group and the appearance state of its widgets.  Each widget shows
+// the state if it has an appearance for it, and Off otherwise.  Checkboxes without appearances get them, and so do
+// radio buttons whose export value is the state.
+func (this *FormFiller) setButtonState(field *FormField, state string) error {
+	dict, err := this.editField(field)
+	if err != nil {
+		return err
+	}
+	dict["/V"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: "/" + state}
+
+	for i := range field.Widgets {
+		widget := &field.Widgets[i]
+		if widget.id == 0 {
+			continue
+		}
+
+		obj, err := this.update.EditObject(widget.id, widget.gen)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read widget annotation")
+		}
+		widgetDict, ok := obj.AsDict()
+		if !ok {
+			continue
+		}
+
+		if state != "Off" && !this.hasOnState(obj) && (field.Type == FormFieldCheckbox || radioWidgetState(field, i, state)) {
+			if err := this.setButtonAppearance(field, obj, state); err != nil {
+				return err
+			}
+		}
+
+		widget.State = "Off"
+		if ap, err := this.reader.Resolve(obj.Key("/AP")); err == nil {
+			if normal, err := this.reader.Resolve(ap.Key("/N")); err == nil && normal.Key("/"+state) != nil {
+				widget.State = state
+			}
+		}
+		widgetDict["/AS"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: "/" + widget.State}
+	}
+
+	field.Value = state
+	field.Values = []string{state}
+
+	return nil
+}
+
+// Check whether the i-th widget of a radio button group turns on with a state, by its export value (/Opt) or, without
+// export values, by its index
+func radioWidgetState(field *FormField, i int, state string) bool {
+	if i < len(field.Options) {
+		return field.Options[i].Export == state
+	}
+	return strconv.Itoa(i) == state
+}
+
+// Check whether a checkbox or radio button widget has an appearance for a state other than Off
+func (this *FormFiller) hasOnState(widget *PdfValue) bool {
+	ap, err := this.reader.Resolve(widget.Key("/AP"))
+	if err != nil {
+		return false
+	}
+	normal, err := this.reader.Resolve(ap.Key("/N"))
+	if err != nil {
+		return false
+	}
+
+	for _, state := range normal.Keys() {
+		if state != "/Off" {
+			return true
+		}
+	}
+	return false
+}
+
+// Get a modifiable copy of a field dictionary
+func (this *FormFiller) editField(field *FormField) (map[string]*PdfValue, error) {
+	obj, err := this.update.EditObject(field.id, field.gen)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read form field")
+	}
+
+	dict, ok := obj.AsDict()
+	if !ok {
+		return nil, errors.New("Form field " + field.Name + " is not a dictionary")
+	}
+
+	return dict, nil
+}
+
+// Set /NeedAppearances in the interactive form dictionary, asking viewers to regenerate the appearances of the
+// fields that still show their previous values
+func (this *FormFiller) setNeedAppearances() error {
+	catalog := this.reader.catalog
+	acroFormSpec := catalog.Key("/AcroForm")
+
+	var obj *PdfValue
+	var err error
+	if acroFormSpec.IsRef() {
+		obj, err = this.update.EditObject(acroFormSpec.Id, acroFormSpec.Gen)
+	} else {
+		// The form dictionary is part of the catalog
+		obj, err = this.update.EditObject(catalog.Id, catalog.Gen)
+		if err == nil {
+			obj = obj.Key("/AcroForm")
+		}
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to read AcroForm")
+	}
+
+	dict, ok := obj.AsDict()
+	if !ok {
+		return errors.New("AcroForm is not a dictionary")
+	}
+	dict["/NeedAppearances"] = &PdfValue{Type: PDF_TYPE_BOOLEAN, Bool: true}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package gofpdi
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestFormFiller(t *testing.T) {
+	reader := readTestFile(t, "form.pdf")
+
+	filler, err := NewFormFiller(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := filler.SetText("person.name", "Zoë"); err != nil {
+		t.Fatal(err)
+	}
+	if err := filler.SetCheckbox("agree", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := filler.SetRadio("color", "A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := filler.SetChoice("fruit", "b", "Cherry"); err != nil {
+		t.Fatal(err)
+	}
+	if err := filler.SetChoice("country", "Japan"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid changes are rejected without changing the field
+	errorTests := []struct {
+		name string
+		set  func() error
+		err  error
+	}{
+		{"unknown field", func() error { return filler.SetText("nothing", "x") }, ErrFieldNotFound},
+		{"text of a checkbox", func() error { return filler.SetText("agree", "x") }, ErrInvalidFieldValue},
+		{"text too long", func() error { return filler.SetText("title", string(make([]byte, 41))) }, ErrInvalidFieldValue},
+		{"unknown radio state", func() error { return filler.SetRadio("color", "C") }, ErrInvalidFieldValue},
+		{"unknown option", func() error { return filler.SetChoice("country", "Spain") }, ErrInvalidFieldValue},
+		{"two options of a combo box", func() error { return filler.SetChoice("country", "France", "Japan") }, ErrInvalidFieldValue},
+	}
+	for _, test := range errorTests {
+		if err := test.set(); !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := filler.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// The update is appended to the original file
+	original, err := ioutil.ReadFile("testdata/form.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), original) {
+		t.Error("the original document was changed")
+	}
+
+	filled, err := NewPdfReaderFromStream(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := filled.TerminalFormFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string][]string{
+		"title":       {"Hello (world)"},
+		"person.name": {"Zoë"},
+		"agree":       {"Off"},
+		"color":       {"A"},
+		"fruit":       {"b", "Cherry"},
+		"country":     {"Japan"},
+	}
+	for name, want := range values {
+		if got := fields[name].Values; !reflect.DeepEqual(got, want) {
+			t.Errorf("field %s values %q, want %q", name, got, want)
+		}
+	}
+
+	// The appearance states of the buttons follow the values
+	if state := fields["agree"].Widgets[0].State; state != "Off" {
+		t.Errorf("checkbox state %s, want Off", state)
+	}
+	if a, b := fields["color"].Widgets[0].State, fields["color"].Widgets[1].State; a != "A" || b != "Off" {
+		t.Errorf("radio states %s and %s, want A and Off", a, b)
+	}
+}
@@ -0,0 +1,478 @@
+package gofpdi
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Types of form fields
+const (
+	FormFieldText       = "text"
+	FormFieldCheckbox   = "checkbox"
+	FormFieldRadio      = "radio"
+	FormFieldPushButton = "pushbutton"
+	FormFieldChoice     = "choice"
+	FormFieldSignature  = "signature"
+)
+
+// Form field flags (/Ff)
+const (
+	FieldFlagReadOnly        = 1 << 0
+	FieldFlagRequired        = 1 << 1
+	FieldFlagNoExport        = 1 << 2
+	FieldFlagMultiline       = 1 << 12
+	FieldFlagPassword        = 1 << 13
+	FieldFlagNoToggleToOff   = 1 << 14
+	FieldFlagRadio           = 1 << 15
+	FieldFlagPushButton      = 1 << 16
+	FieldFlagCombo           = 1 << 17
+	FieldFlagEdit            = 1 << 18
+	FieldFlagFileSelect      = 1 << 20
+	FieldFlagMultiSelect     = 1 << 21
+	FieldFlagComb            = 1 << 24
+	FieldFlagRadiosInUnison  = 1 << 25
+	FieldFlagCommitOnSelChng = 1 << 26
+)
+
+// FormField is a field of an interactive form (/AcroForm).  Fields that are not terminal have Kids; their Type,
+// Flags and values are inherited by their kids unless the kids define them.
+type FormField struct {
+	Name          string   // fully qualified name, the partial names of the field and its ancestors joined by "."
+	PartialName   string   // /T
+	AlternateName string   // /TU, the name shown to users
+	Type          string   // one of the FormField* constants, empty for non-terminal fields without /FT
+	Flags         int      // /Ff, see the FieldFlag* constants
+	Value         string   // /V: the text, selected option, or state of a button without the slash (e.g. "Off")
+	Values        []string // all selected options of a multiple selection choice field
+	DefaultValue  string   // /DV
+	Options       []FieldOption
+	States        []string // states that turn a checkbox or radio button on, as used in Value (e.g. "Yes")
+	MaxLen        int      // maximum length of a text field, 0 if unlimited
+	Widgets       []FormWidget
+	Kids          []*FormField
+
+	id  int
+	gen int
+}
+
+// FieldOption is an option of a choice field (or the export value of a checkbox or radio button)
+type FieldOption struct {
+	Export  string
+	Display string
+}
+
+// FormWidget is a widget annotation showing a form field on a page
+type FormWidget struct {
+	Page  int        // page number, 0 if the widget is not on any page
+	Rect  [4]float64 // lower left x, lower left y, upper right x, upper right y
+	State string     // appearance state of a checkbox or radio button (/AS) without the slash
+
+	id  int
+	gen int
+}
+
+// ReadOnly reports whether the field may not be changed by users
+func (this *FormField) ReadOnly() bool {
+	return this.Flags&FieldFlagReadOnly != 0
+}
+
+// Required reports whether the field must have a value when the form is submitted
+func (this *FormField) Required() bool {
+	return this.Flags&FieldFlagRequired != 0
+}
+
+// Field attributes inherited by kids from their parent field
+type fieldInherited struct {
+	name  string
+	ft    string
+	flags *PdfValue
+	v     *PdfValue
+	dv    *PdfValue
+	opt   *PdfValue
+}
+
+// FormFields returns the fields of the interactive form of the document as a tree, or an empty slice if the
+// document has no form
+func (this *PdfReader) FormFields() ([]*FormField, error) {
+	acroFormSpec := this.catalog.Key("/AcroForm")
+	if acroFormSpec == nil {
+		return make([]*FormField, 0), nil
+	}
+
+	acroForm, err := this.Resolve(acroFormSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve AcroForm")
+	}
+
+	fieldsSpec := acroForm.Key("/Fields")
+	if fieldsSpec == nil {
+		return make([]*FormField, 0), nil
+	}
+
+	fields, err := this.Resolve(fieldsSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve form fields")
+	}
+
+	reader := &formReader{PdfReader: this, visited: make(map[int]bool, 0)}
+
+	return reader.readFields(fields, fieldInherited{})
+}
+
+// FormFields is the same as PdfReader.FormFields
+func (e *Exporter) FormFields() ([]*FormField, error) {
+	return e.reader.FormFields()
+}
+
+// TerminalFormFields returns the fields that hold values (the leaves of the field tree), keyed by their fully
+// qualified name
+func (this *PdfReader) TerminalFormFields() (map[string]*FormField, error) {
+	fields, err := this.FormFields()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*FormField, 0)
+
+	var add func(fields []*FormField)
+	add = func(fields []*FormField) {
+		for _, field := range fields {
+			if len(field.Kids) == 0 {
+				result[field.Name] = field
+			}
+			add(field.Kids)
+		}
+	}
+	add(fields)
+
+	return result, nil
+}
+
+// State of a form field tree walk
+type formReader struct {
+	*PdfReader
+	visited map[int]bool
+
+	// Page numbers of annotations, for widgets without /P
+	annotPages map[int]int
+}
+
+// Read the fields of a /Fields or /Kids array
+func (this *formReader) readFields(array *PdfValue, parent fieldInherited) ([]*FormField, error) {
+	result := make([]*FormField, 0)
+
+	kids, _ := array.AsArray()
+	for _, kidSpec := range kids {
+		if !kidSpec.IsRef() || this.visited[kidSpec.Id] {
+			continue
+		}
+		this.visited[kidSpec.Id] = true
+
+		kid, err := this.Resolve(kidSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve form field")
+		}
+
+		// Kids without a name are widgets of their parent, which is handled by readField
+		if kid.Key("/T") == nil && parent.name != "" {
+			continue
+		}
+
+		field, err := this.readField(kid, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, field)
+	}
+
+	return result, nil
+}
+
+// Read a field along with its widgets and kids
+func (this *formReader) readField(obj *PdfValue, parent fieldInherited) (*FormField, error) {
+	field := &FormField{id: obj.Id, gen: obj.Gen}
+
+	field.PartialName = this.text(obj.Key("/T"))
+	field.AlternateName = this.text(obj.Key("/TU"))
+
+	field.Name = field.PartialName
+	if parent.name != "" {
+		field.Name = parent.name + "." + field.PartialName
+	}
+
+	inherited := parent
+	inherited.name = field.Name
+	if ft, ok := obj.Key("/FT").AsName(); ok {
+		inherited.ft = ft
+	}
+	if v := obj.Key("/Ff"); v != nil {
+		inherited.flags = v
+	}
+	if v := obj.Key("/V"); v != nil {
+		inherited.v = v
+	}
+	if v := obj.Key("/DV"); v != nil {
+		inherited.dv = v
+	}
+	if v := obj.Key("/Opt"); v != nil {
+		inherited.opt = v
+	}
+
+	if inherited.flags != nil {
+		flags, err := this.Resolve(inherited.flags)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve field flags")
+		}
+		field.Flags, _ = flags.AsInt()
+	}
+
+	field.Type = fieldType(inherited.ft, field.Flags)
+
+	if maxLen, err := this.Resolve(obj.Key("/MaxLen")); err == nil {
+		field.MaxLen, _ = maxLen.AsInt()
+	}
+
+	// Values
+	if inherited.v != nil {
+		values, err := this.fieldValues(inherited.v)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve field value")
+		}
+		if len(values) > 0 {
+			field.Value = values[0]
+		}
+		field.Values = values
+	}
+	if inherited.dv != nil {
+		values, err := this.fieldValues(inherited.dv)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve field default value")
+		}
+		if len(values) > 0 {
+			field.DefaultValue = values[0]
+		}
+	}
+
+	// Options
+	if inherited.opt != nil {
+		opts, err := this.Resolve(inherited.opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve field options")
+		}
+
+		array, _ := opts.AsArray()
+		for _, optSpec := range array {
+			opt, err := this.Resolve(optSpec)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to resolve field option")
+			}
+
+			if pair, ok := opt.AsArray(); ok && len(pair) == 2 {
+				field.Options = append(field.Options, FieldOption{Export: this.text(pair[0]), Display: this.text(pair[1])})
+			} else {
+				text := this.text(opt)
+				field.Options = append(field.Options, FieldOption{Export: text, Display: text})
+			}
+		}
+	}
+
+	// The field dictionary is merged with its widget if it has a single one, otherwise its kids without /T
+	// are its widgets
+	if isWidget(obj) {
+		if err := this.addWidget(field, obj); err != nil {
+			return nil, err
+		}
+	}
+
+	kidsSpec := obj.Key("/Kids")
+	if kidsSpec == nil {
+		return field, nil
+	}
+
+	kids, err := this.Resolve(kidsSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve field kids")
+	}
+
+	array, _ := kids.AsArray()
+	for _, kidSpec := range array {
+		kid, err := this.Resolve(kidSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve form field")
+		}
+		if kid.Key("/T") == nil {
+			if err := this.addWidget(field, kid); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	field.Kids, err = this.readFields(kids, inherited)
+	if err != nil {
+		return nil, err
+	}
+
+	return field, nil
+}
+
+// Add a widget annotation to a field, along with the states of a checkbox or radio button
+func (this *formReader) addWidget(field *FormField, obj *PdfValue) error {
+	widget := FormWidget{id: obj.Id, gen: obj.Gen}
+
+	if rect, err := this.Resolve(obj.Key("/Rect")); err == nil {
+		if coords, ok := rect.AsArray(); ok && len(coords) == 4 {
+			var r [4]float64
+			for i := 0; i < 4; i++ {
+				r[i], _ = coords[i].AsFloat()
+			}
+			widget.Rect = [4]float64{math.Min(r[0], r[2]), math.Min(r[1], r[3]), math.Max(r[0], r[2]), math.Max(r[1], r[3])}
+		}
+	}
+
+	if state, ok := obj.Key("/AS").AsName(); ok {
+		widget.State = strings.TrimPrefix(state, "/")
+	}
+
+	page, err := this.widgetPage(obj)
+	if err != nil {
+		return err
+	}
+	widget.Page = page
+
+	field.Widgets = append(field.Widgets, widget)
+
+	// The names of the normal appearances other than Off are the states that turn the button on
+	if field.Type == FormFieldCheckbox || field.Type == FormFieldRadio {
+		ap, err := this.Resolve(obj.Key("/AP"))
+		if err != nil {
+			return nil
+		}
+		normal, err := this.Resolve(ap.Key("/N"))
+		if err != nil {
+			return nil
+		}
+		for _, state := range normal.Keys() {
+			state = strings.TrimPrefix(state, "/")
+			if state != "Off" && !in_array(state, field.States) {
+				field.States = append(field.States, state)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Get the page number of a widget annotation from its /P entry, or from the /Annots of the pages
+func (this *formReader) widgetPage(obj *PdfValue) (int, error) {
+	if p := obj.Key("/P"); p != nil && p.IsRef() {
+		pageno, err := this.pageNumber(p.Id)
+		if err != nil || pageno != 0 {
+			return pageno, err
+		}
+	}
+
+	if this.annotPages == nil {
+		this.annotPages = make(map[int]int, 0)
+
+		for pageno := 1; pageno <= this.numPages(); pageno++ {
+			page, err := this.getPage(pageno)
+			if err != nil {
+				return 0, err
+			}
+
+			annots, err := this.Resolve(page.Key("/Annots"))
+			if err != nil {
+				continue
+			}
+			array, _ := annots.AsArray()
+			for _, annot := range array {
+				if annot.IsRef() {
+					this.annotPages[annot.Id] = pageno
+				}
+			}
+		}
+	}
+
+	return this.annotPages[obj.Id], nil
+}
+
+// Get the values of /V or /DV: a text string, the name of a button state, or an array of selected options
+func (this *formReader) fieldValues(spec *PdfValue) ([]string, error) {
+	value, err := this.Resolve(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if name, ok := value.AsName(); ok {
+		return []string{strings.TrimPrefix(name, "/")}, nil
+	}
+
+	if array, ok := value.AsArray(); ok {
+		values := make([]string, 0, len(array))
+		for _, v := range array {
+			values = append(values, this.text(v))
+		}
+		return values, nil
+	}
+
+	// Long text values may be stored in a stream
+	if value.IsStream() {
+		data, err := this.decodeStream(value)
+		if err != nil {
+			return nil, err
+		}
+		return []string{decodeTextString(data)}, nil
+	}
+
+	if text, ok := value.AsText(); ok {
+		return []string{text}, nil
+	}
+
+	return nil, nil
+}
+
+// Get a text string, resolving it if necessary.  Returns an empty string for other values.
+func (this *formReader) text(spec *PdfValue) string {
+	if spec == nil {
+		return ""
+	}
+
+	value, err := this.Resolve(spec)
+	if err != nil {
+		return ""
+	}
+
+	text, _ := value.AsText()
+	return text
+}
+
+// Check whether a dictionary is a widget annotation
+func isWidget(obj *PdfValue) bool {
+	subtype, _ := obj.Key("/Subtype").AsName()
+	return subtype == "/Widget" || (subtype == "" && obj.Key("/Rect") != nil)
+}
+
+// Get the type of a field from its /FT and flags
+func fieldType(ft string, flags int) string {
+	switch ft {
+	case "/Tx":
+		return FormFieldText
+	case "/Btn":
+		if flags&FieldFlagPushButton != 0 {
+			return FormFieldPushButton
+		}
+		if flags&FieldFlagRadio != 0 {
+			return FormFieldRadio
+		}
+		return FormFieldCheckbox
+	case "/Ch":
+		return FormFieldChoice
+	case "/Sig":
+		return FormFieldSignature
+	}
+
+	return ""
+}
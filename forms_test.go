@@ -0,0 +1,79 @@
+package gofpdi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormFields(t *testing.T) {
+	reader := readTestFile(t, "form.pdf")
+
+	fields, err := reader.FormFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 7 || fields[1].Name != "person" || len(fields[1].Kids) != 2 {
+		t.Fatalf("field tree %+v", fields)
+	}
+
+	terminal, err := reader.TerminalFormFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Kids inherit the type of their parent, and widgets without /P are found in the /Annots of their page
+	tests := []struct {
+		name   string
+		typ    string
+		values []string
+		page   int
+	}{
+		{"title", FormFieldText, []string{"Hello (world)"}, 1},
+		{"person.name", FormFieldText, []string{"Jö"}, 1},
+		{"person.age", FormFieldText, nil, 1},
+		{"agree", FormFieldCheckbox, []string{"Yes"}, 1},
+		{"color", FormFieldRadio, []string{"B"}, 2},
+		{"fruit", FormFieldChoice, []string{"a", "Cherry"}, 2},
+		{"country", FormFieldChoice, []string{"Germany"}, 2},
+		{"signature", FormFieldSignature, nil, 2},
+	}
+
+	if len(terminal) != len(tests) {
+		t.Errorf("%d terminal fields, want %d", len(terminal), len(tests))
+	}
+	for _, test := range tests {
+		field, ok := terminal[test.name]
+		if !ok {
+			t.Errorf("field %s not found", test.name)
+			continue
+		}
+		if field.Type != test.typ || !reflect.DeepEqual(field.Values, test.values) {
+			t.Errorf("field %s is a %s field with values %q, want a %s field with values %q", test.name, field.Type, field.Values,
+				test.typ, test.values)
+		}
+		if len(field.Widgets) == 0 || field.Widgets[0].Page != test.page {
+			t.Errorf("field %s widgets %+v, want one on page %d", test.name, field.Widgets, test.page)
+		}
+	}
+
+	title := terminal["title"]
+	if title.AlternateName != "Document title" || title.MaxLen != 40 || !title.Required() || title.ReadOnly() {
+		t.Errorf("title %+v", title)
+	}
+	if !terminal["person.age"].ReadOnly() {
+		t.Error("person.age is not read-only")
+	}
+	if age := terminal["person.age"].Widgets[0].Rect; age != [4]float64{100, 540, 300, 560} {
+		t.Errorf("person.age rectangle %v is not normalized", age)
+	}
+
+	color := terminal["color"]
+	if !reflect.DeepEqual(color.States, []string{"A", "B"}) || color.Widgets[0].State != "Off" || color.Widgets[1].State != "B" {
+		t.Errorf("color states %v, widgets %+v", color.States, color.Widgets)
+	}
+
+	want := []FieldOption{{"a", "Apple"}, {"b", "Banana"}, {"Cherry", "Cherry"}}
+	if !reflect.DeepEqual(terminal["fruit"].Options, want) {
+		t.Errorf("fruit options %+v, want %+v", terminal["fruit"].Options, want)
+	}
+}
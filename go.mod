@@ -1,8 +1,8 @@
 module github.com/tim-timpani/gofpdi
 
-go 1.12
+go 1.13
 
 require (
-	github.com/pkg/errors v0.8.1
+	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.0
 )
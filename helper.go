@@ -1,7 +1,10 @@
 package gofpdi
 
 import (
+	"encoding/hex"
+	"fmt"
 	"strings"
+	"unicode/utf16"
 )
 
 // Determine if a value is numeric
@@ -19,6 +22,9 @@ func is_numeric(val interface{}) bool {
 		// Trim any whitespace
 		str = strings.TrimSpace(str)
 		//fmt.Println(str)
+		if str == "" {
+			return false
+		}
 		if str[0] == '-' || str[0] == '+' {
 			if len(str) == 1 {
 				return false
@@ -127,3 +133,264 @@ func filterPaeth(cdat, pdat []byte, bytesPerPixel int) {
 		}
 	}
 }
+
+// Get the raw bytes of a literal or hex string value
+func pdfStringBytes(value *PdfValue) []byte {
+	switch value.Type {
+	case PDF_TYPE_STRING:
+		return unescapeLiteralString(value.String)
+	case PDF_TYPE_HEX:
+		return decodeHexString(value.String)
+	}
+
+	return nil
+}
+
+// Replace the contents of a literal or hex string value with raw bytes
+func setPdfStringBytes(value *PdfValue, data []byte) {
+	switch value.Type {
+	case PDF_TYPE_STRING:
+		value.String = escapeLiteralString(data)
+	case PDF_TYPE_HEX:
+		value.String = hex.EncodeToString(data)
+	}
+}
+
+// Decode the contents of a hex string, ignoring whitespace.  A missing final digit is assumed to be 0.
+func decodeHexString(s string) []byte {
+	result := make([]byte, 0, len(s)/2)
+	var b byte
+	var odd bool
+
+	for i := 0; i < len(s); i++ {
+		var n byte
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			n = c - '0'
+		case c >= 'a' && c <= 'f':
+			n = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			n = c - 'A' + 10
+		default:
+			continue
+		}
+
+		if odd {
+			result = append(result, b|n)
+		} else {
+			b = n << 4
+		}
+		odd = !odd
+	}
+
+	if odd {
+		result = append(result, b)
+	}
+
+	return result
+}
+
+// Decode the escape sequences of a literal string (without the enclosing parentheses)
+func unescapeLiteralString(s string) []byte {
+	result := make([]byte, 0, len(s))
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		// Normalize end-of-line markers to a single line feed
+		if c == '\r' {
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			result = append(result, '\n')
+			continue
+		}
+
+		if c != '\\' || i+1 >= len(s) {
+			result = append(result, c)
+			continue
+		}
+
+		i++
+		c = s[i]
+		switch c {
+		case 'n':
+			result = append(result, '\n')
+		case 'r':
+			result = append(result, '\r')
+		case 't':
+			result = append(result, '\t')
+		case 'b':
+			result = append(result, '\b')
+		case 'f':
+			result = append(result, '\f')
+		case '\r':
+			// Line continuation
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		case '\n':
+			// Line continuation
+		default:
+			if c >= '0' && c <= '7' {
+				// Up to three octal digits
+				n := int(c - '0')
+				for j := 0; j < 2 && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '7'; j++ {
+					i++
+					n = n*8 + int(s[i]-'0')
+				}
+				result = append(result, byte(n))
+			} else {
+				// \(, \), \\ and unknown escapes map to the character itself
+				result = append(result, c)
+			}
+		}
+	}
+
+	return result
+}
+
+// Escape raw bytes for use in a literal string (without the enclosing parentheses)
+func escapeLiteralString(data []byte) string {
+	var buf strings.Builder
+
+	for _, c := range data {
+		switch c {
+		case '(', ')', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\r':
+			buf.WriteString("\\r")
+		case '\n':
+			buf.WriteString("\\n")
+		default:
+			if c < 32 || c > 126 {
+				buf.WriteString(fmt.Sprintf("\\%03o", c))
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// Characters of PDFDocEncoding that differ from ISO Latin-1 (PDF 32000-1:2008, Annex D.2)
+var pdfDocEncoding = map[byte]rune{
+	0x18: 0x02d8, 0x19: 0x02c7, 0x1a: 0x02c6, 0x1b: 0x02d9, 0x1c: 0x02dd, 0x1d: 0x02db, 0x1e: 0x02da, 0x1f: 0x02dc,
+	0x80: 0x2022, 0x81: 0x2020, 0x82: 0x2021, 0x83: 0x2026, 0x84: 0x2014, 0x85: 0x2013, 0x86: 0x0192, 0x87: 0x2044,
+	0x88: 0x2039, 0x89: 0x203a, 0x8a: 0x2212, 0x8b: 0x2030, 0x8c: 0x201e, 0x8d: 0x201c, 0x8e: 0x201d, 0x8f: 0x2018,
+	0x90: 0x2019, 0x91: 0x201a, 0x92: 0x2122, 0x93: 0xfb01, 0x94: 0xfb02, 0x95: 0x0141, 0x96: 0x0152, 0x97: 0x0160,
+	0x98: 0x0178, 0x99: 0x017d, 0x9a: 0x0131, 0x9b: 0x0142, 0x9c: 0x0153, 0x9d: 0x0161, 0x9e: 0x017e, 0xa0: 0x20ac,
+}
+
+// Decode a text string, which is encoded in UTF-16BE (with a byte order mark), UTF-8 (with a byte order mark)
+// or PDFDocEncoding
+func decodeTextString(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xfe && data[1] == 0xff {
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		}
+		return stripLanguageEscapes(string(utf16.Decode(units)))
+	}
+
+	if len(data) >= 3 && data[0] == 0xef && data[1] == 0xbb && data[2] == 0xbf {
+		return stripLanguageEscapes(string(data[3:]))
+	}
+
+	var buf strings.Builder
+	for _, c := range data {
+		if r, ok := pdfDocEncoding[c]; ok {
+			buf.WriteRune(r)
+		} else {
+			buf.WriteRune(rune(c))
+		}
+	}
+
+	return buf.String()
+}
+
+// Remove the language and country codes that Unicode text strings may embed between two U+001B characters
+func stripLanguageEscapes(s string) string {
+	for {
+		start := strings.IndexRune(s, 0x1b)
+		if start < 0 {
+			return s
+		}
+
+		end := strings.IndexRune(s[start+1:], 0x1b)
+		if end < 0 {
+			return s[:start]
+		}
+
+		s = s[:start] + s[start+1+end+1:]
+	}
+}
+
+// Encode a text string in PDFDocEncoding if possible, or in UTF-16BE with a byte order mark otherwise
+func encodeTextString(s string) []byte {
+	reverse := make(map[rune]byte, len(pdfDocEncoding))
+	for b, r := range pdfDocEncoding {
+		reverse[r] = b
+	}
+
+	result := make([]byte, 0, len(s))
+	for _, r := range s {
+		if b, ok := reverse[r]; ok {
+			result = append(result, b)
+		} else if _, redefined := pdfDocEncoding[byte(r)]; r < 256 && !redefined && r != 0x7f && r != 0x9f && r != 0xad {
+			result = append(result, byte(r))
+		} else {
+			result = nil
+			break
+		}
+	}
+	if result != nil {
+		return result
+	}
+
+	result = []byte{0xfe, 0xff}
+	for _, u := range utf16.Encode([]rune(s)) {
+		result = append(result, byte(u>>8), byte(u))
+	}
+
+	return result
+}
+
+// Create a string value holding a text string
+func newTextStringValue(s string) *PdfValue {
+	data := encodeTextString(s)
+	if len(data) >= 2 && data[0] == 0xfe && data[1] == 0xff {
+		return &PdfValue{Type: PDF_TYPE_HEX, String: hex.EncodeToString(data)}
+	}
+	return &PdfValue{Type: PDF_TYPE_STRING, String: escapeLiteralString(data)}
+}
+
+// Make a deep copy of a value, so that it can be modified without affecting the (cached) original.
+// Indirect references are copied as references; the objects they refer to are not copied.
+func copyPdfValue(value *PdfValue) *PdfValue {
+	if value == nil {
+		return nil
+	}
+
+	result := *value
+
+	if value.Dictionary != nil {
+		result.Dictionary = make(map[string]*PdfValue, len(value.Dictionary))
+		for k, v := range value.Dictionary {
+			result.Dictionary[k] = copyPdfValue(v)
+		}
+	}
+	if value.Array != nil {
+		result.Array = make([]*PdfValue, len(value.Array))
+		for i, v := range value.Array {
+			result.Array[i] = copyPdfValue(v)
+		}
+	}
+	result.Value = copyPdfValue(value.Value)
+	result.Stream = copyPdfValue(value.Stream)
+
+	return &result
+}
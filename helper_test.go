@@ -0,0 +1,91 @@
+package gofpdi
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+// Read a document from testdata
+func readTestFile(t *testing.T, file string, opts ...ReaderOption) *PdfReader {
+	t.Helper()
+
+	data, err := ioutil.ReadFile("testdata/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewPdfReaderFromStream(bytes.NewReader(data), opts...)
+	if err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+
+	return reader
+}
+
+// Get the text of a page
+func pageText(t *testing.T, reader *PdfReader, pageno int) string {
+	t.Helper()
+
+	exporter, err := NewExporterFromReader(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := exporter.GetPagePlainText(pageno)
+	if err != nil {
+		t.Fatalf("page %d: %v", pageno, err)
+	}
+
+	return text
+}
+
+// Check the page count and the text of the pages of the documents in testdata, which have "Hello page N" on page N
+func checkPages(t *testing.T, name string, reader *PdfReader, pagenos ...int) {
+	t.Helper()
+
+	if reader.NumPages() != len(pagenos) {
+		t.Fatalf("%s: %d pages, want %d", name, reader.NumPages(), len(pagenos))
+	}
+
+	for i, pageno := range pagenos {
+		want := fmt.Sprintf("Hello page %d\n", pageno)
+		if got := pageText(t, reader, i+1); got != want {
+			t.Errorf("%s: page %d text %q, want %q", name, i+1, got, want)
+		}
+	}
+}
+
+// Build a document from objects numbered from 1, with a cross-reference table and object 1 as the catalog
+func buildTestPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// Read a document built by buildTestPDF
+func readTestPDF(t *testing.T, objects ...string) *PdfReader {
+	t.Helper()
+
+	reader, err := NewPdfReaderFromStream(bytes.NewReader(buildTestPDF(objects...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reader
+}
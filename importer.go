@@ -3,6 +3,8 @@ package gofpdi
 import (
 	"fmt"
 	"io"
+
+	"github.com/pkg/errors"
 )
 
 // The Importer class to be used by a pdf generation library
@@ -14,6 +16,8 @@ type Importer struct {
 	tplN          int
 	writer        *PdfWriter
 	importedPages map[string]int
+	readerOptions []ReaderOption
+	importOptions []ImportOption
 }
 
 type TplInfo struct {
@@ -61,58 +65,115 @@ func (this *Importer) init() {
 	this.importedPages = make(map[string]int, 0)
 }
 
+// Set options (e.g. WithPassword) used for readers created by SetSourceFile and SetSourceStream
+func (this *Importer) SetReaderOptions(opts ...ReaderOption) {
+	this.readerOptions = opts
+}
+
+// Set options (e.g. WithAnnotations) used when importing pages
+func (this *Importer) SetImportOptions(opts ...ImportOption) {
+	this.importOptions = opts
+}
+
 func (this *Importer) SetSourceFile(f string) {
-	this.sourceFile = f
+	if err := this.SetSourceFileErr(f); err != nil {
+		panic(err)
+	}
+}
 
+// Same as SetSourceFile, but returns an error instead of panicking
+func (this *Importer) SetSourceFileErr(f string) (err error) {
 	// If reader hasn't been instantiated, do that now
-	if _, ok := this.readers[this.sourceFile]; !ok {
-		reader, err := NewPdfReader(this.sourceFile)
+	if _, ok := this.readers[f]; !ok {
+		reader, err := NewPdfReader(f, this.readerOptions...)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		this.readers[this.sourceFile] = reader
+		this.readers[f] = reader
 	}
 
-	// If writer hasn't been instantiated, do that now
-	if _, ok := this.writers[this.sourceFile]; !ok {
-		writer, err := NewPdfWriter("")
-		if err != nil {
-			panic(err)
-		}
+	return this.setSource(f)
+}
 
-		// Make the next writer start template numbers at this.tplN
-		writer.SetTplIdOffset(this.tplN)
-		this.writers[this.sourceFile] = writer
+func (this *Importer) SetSourceStream(rs *io.ReadSeeker) {
+	if err := this.SetSourceStreamErr(rs); err != nil {
+		panic(err)
 	}
 }
 
-func (this *Importer) SetSourceStream(rs *io.ReadSeeker) {
-	this.sourceFile = fmt.Sprintf("%v", rs)
+// Same as SetSourceStream, but returns an error instead of panicking
+func (this *Importer) SetSourceStreamErr(rs *io.ReadSeeker) (err error) {
+	if rs == nil || *rs == nil {
+		return errors.New("No source stream given")
+	}
 
-	if _, ok := this.readers[this.sourceFile]; !ok {
-		reader, err := NewPdfReaderFromStream(*rs)
+	sourceFile := fmt.Sprintf("%v", rs)
+
+	if _, ok := this.readers[sourceFile]; !ok {
+		reader, err := NewPdfReaderFromStream(*rs, this.readerOptions...)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		this.readers[this.sourceFile] = reader
+		this.readers[sourceFile] = reader
+	}
+
+	return this.setSource(sourceFile)
+}
+
+// Use an already parsed reader as source, identified by name.  A reader can be shared by importers
+// running in different goroutines.
+func (this *Importer) SetSourceReader(name string, reader *PdfReader) {
+	if err := this.SetSourceReaderErr(name, reader); err != nil {
+		panic(err)
 	}
+}
 
+// Same as SetSourceReader, but returns an error instead of panicking
+func (this *Importer) SetSourceReaderErr(name string, reader *PdfReader) error {
+	if reader == nil {
+		return errors.New("No source reader given")
+	}
+
+	if existing, ok := this.readers[name]; ok && existing != reader {
+		return errors.New("A different source has already been set with name: " + name)
+	}
+	this.readers[name] = reader
+
+	return this.setSource(name)
+}
+
+// Make sourceFile, whose reader has already been created, the current source
+func (this *Importer) setSource(sourceFile string) error {
 	// If writer hasn't been instantiated, do that now
-	if _, ok := this.writers[this.sourceFile]; !ok {
+	if _, ok := this.writers[sourceFile]; !ok {
 		writer, err := NewPdfWriter("")
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		// Make the next writer start template numbers at this.tplN
 		writer.SetTplIdOffset(this.tplN)
-		this.writers[this.sourceFile] = writer
+		this.writers[sourceFile] = writer
 	}
+
+	this.sourceFile = sourceFile
+
+	return nil
 }
 
-func (this *Importer) GetNumPages() int {
-	result, err := this.GetReader().getNumPages()
+// Get the reader and writer of the current source
+func (this *Importer) getSource() (*PdfReader, *PdfWriter, error) {
+	reader := this.GetReader()
+	writer := this.GetWriter()
+	if reader == nil || writer == nil {
+		return nil, nil, errors.New("No source file or stream has been set")
+	}
 
+	return reader, writer, nil
+}
+
+func (this *Importer) GetNumPages() int {
+	result, err := this.GetNumPagesErr()
 	if err != nil {
 		panic(err)
 	}
@@ -120,9 +181,18 @@ func (this *Importer) GetNumPages() int {
 	return result
 }
 
-func (this *Importer) GetPageSizes() map[int]map[string]map[string]float64 {
-	result, err := this.GetReader().getAllPageBoxes(1.0)
+// Same as GetNumPages, but returns an error instead of panicking
+func (this *Importer) GetNumPagesErr() (result int, err error) {
+	reader, _, err := this.getSource()
+	if err != nil {
+		return 0, err
+	}
 
+	return reader.getNumPages()
+}
+
+func (this *Importer) GetPageSizes() map[int]map[string]map[string]float64 {
+	result, err := this.GetPageSizesErr()
 	if err != nil {
 		panic(err)
 	}
@@ -130,23 +200,66 @@ func (this *Importer) GetPageSizes() map[int]map[string]map[string]float64 {
 	return result
 }
 
+// Same as GetPageSizes, but returns an error instead of panicking
+func (this *Importer) GetPageSizesErr() (result map[int]map[string]map[string]float64, err error) {
+	reader, _, err := this.getSource()
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.getAllPageBoxes(1.0)
+}
+
+// GetPageBoxes returns the effective boxes, user unit and rotation of a page of the current source file
+func (this *Importer) GetPageBoxes(pageno int) (result *PageBoxes, err error) {
+	reader, _, err := this.getSource()
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.PageBoxes(pageno)
+}
+
 func (this *Importer) ImportPage(pageno int, box string) int {
-	// If page has already been imported, return existing tplN
-	pageNameNumber := fmt.Sprintf("%s-%04d", this.sourceFile, pageno)
+	result, err := this.ImportPageErr(pageno, box)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+// Same as ImportPage, but returns an error instead of panicking
+func (this *Importer) ImportPageErr(pageno int, box string) (result int, err error) {
+	reader, writer, err := this.getSource()
+	if err != nil {
+		return -1, err
+	}
+
+	// If page has already been imported with the same options, return existing tplN
+	options := &importOptions{}
+	for _, opt := range this.importOptions {
+		opt(options)
+	}
+	pageNameNumber := fmt.Sprintf("%s-%04d-%+v", this.sourceFile, pageno, *options)
 	if _, ok := this.importedPages[pageNameNumber]; ok {
-		return this.importedPages[pageNameNumber]
+		return this.importedPages[pageNameNumber], nil
+	}
+
+	if err := reader.checkPage(pageno); err != nil {
+		return -1, err
 	}
 
-	res, err := this.GetWriter().ImportPage(this.GetReader(), pageno, box)
+	res, err := writer.ImportPage(reader, pageno, box, this.importOptions...)
 	if err != nil {
-		panic(err)
+		return -1, pageError(pageno, err)
 	}
 
 	// Get current template id
 	tplN := this.tplN
 
 	// Set tpl info
-	this.tplMap[tplN] = &TplInfo{SourceFile: this.sourceFile, TemplateId: res, Writer: this.GetWriter()}
+	this.tplMap[tplN] = &TplInfo{SourceFile: this.sourceFile, TemplateId: res, Writer: writer}
 
 	// Increment template id
 	this.tplN++
@@ -154,77 +267,200 @@ func (this *Importer) ImportPage(pageno int, box string) int {
 	// Cache imported page tplN
 	this.importedPages[pageNameNumber] = tplN
 
-	return tplN
+	return tplN, nil
+}
+
+// Import the first page with the given label (e.g. "iv" or "A-3"), see PdfReader.PageLabels
+func (this *Importer) ImportPageByLabel(label string, box string) int {
+	result, err := this.ImportPageByLabelErr(label, box)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+// Same as ImportPageByLabel, but returns an error instead of panicking
+func (this *Importer) ImportPageByLabelErr(label string, box string) (result int, err error) {
+	reader, _, err := this.getSource()
+	if err != nil {
+		return -1, err
+	}
+
+	pageno, err := reader.PageByLabel(label)
+	if err != nil {
+		return -1, err
+	}
+
+	return this.ImportPageErr(pageno, box)
 }
 
 func (this *Importer) SetNextObjectID(objId int) {
-	this.GetWriter().SetNextObjectID(objId)
+	err := this.SetNextObjectIDErr(objId)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Same as SetNextObjectID, but returns an error instead of panicking
+func (this *Importer) SetNextObjectIDErr(objId int) error {
+	_, writer, err := this.getSource()
+	if err != nil {
+		return err
+	}
+
+	writer.SetNextObjectID(objId)
+	return nil
 }
 
 // Put form xobjects and get back a map of template names (e.g. /GOFPDITPL1) and their object ids (int)
 func (this *Importer) PutFormXobjects() map[string]int {
-	res := make(map[string]int, 0)
-	tplNamesIds, err := this.GetWriter().PutFormXobjects(this.GetReader())
+	res, err := this.PutFormXobjectsErr()
 	if err != nil {
 		panic(err)
 	}
+	return res
+}
+
+// Same as PutFormXobjects, but returns an error instead of panicking
+func (this *Importer) PutFormXobjectsErr() (res map[string]int, err error) {
+	reader, writer, err := this.getSource()
+	if err != nil {
+		return nil, err
+	}
+
+	res = make(map[string]int, 0)
+	tplNamesIds, err := writer.PutFormXobjects(reader)
+	if err != nil {
+		return nil, err
+	}
 	for tplName, pdfObjId := range tplNamesIds {
 		res[tplName] = pdfObjId.id
 	}
-	return res
+	return res, nil
 }
 
 // Put form xobjects and get back a map of template names (e.g. /GOFPDITPL1) and their object ids (sha1 hash)
 func (this *Importer) PutFormXobjectsUnordered() map[string]string {
-	this.GetWriter().SetUseHash(true)
-	res := make(map[string]string, 0)
-	tplNamesIds, err := this.GetWriter().PutFormXobjects(this.GetReader())
+	res, err := this.PutFormXobjectsUnorderedErr()
 	if err != nil {
 		panic(err)
 	}
+	return res
+}
+
+// Same as PutFormXobjectsUnordered, but returns an error instead of panicking
+func (this *Importer) PutFormXobjectsUnorderedErr() (res map[string]string, err error) {
+	reader, writer, err := this.getSource()
+	if err != nil {
+		return nil, err
+	}
+
+	writer.SetUseHash(true)
+	res = make(map[string]string, 0)
+	tplNamesIds, err := writer.PutFormXobjects(reader)
+	if err != nil {
+		return nil, err
+	}
 	for tplName, pdfObjId := range tplNamesIds {
 		res[tplName] = pdfObjId.hash
 	}
-	return res
+	return res, nil
 }
 
 // Get object ids (int) and their contents (string)
 func (this *Importer) GetImportedObjects() map[int]string {
-	res := make(map[int]string, 0)
-	pdfObjIdBytes := this.GetWriter().GetImportedObjects()
+	res, err := this.GetImportedObjectsErr()
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Same as GetImportedObjects, but returns an error instead of panicking
+func (this *Importer) GetImportedObjectsErr() (res map[int]string, err error) {
+	_, writer, err := this.getSource()
+	if err != nil {
+		return nil, err
+	}
+
+	res = make(map[int]string, 0)
+	pdfObjIdBytes := writer.GetImportedObjects()
 	for pdfObjId, bytes := range pdfObjIdBytes {
 		res[pdfObjId.id] = string(bytes)
 	}
-	return res
+	return res, nil
 }
 
 // Get object ids (sha1 hash) and their contents ([]byte)
 // The contents may have references to other object hashes which will need to be replaced by the pdf generator library
 // The positions of the hashes (sha1 - 40 characters) can be obtained by calling GetImportedObjHashPos()
 func (this *Importer) GetImportedObjectsUnordered() map[string][]byte {
-	res := make(map[string][]byte, 0)
-	pdfObjIdBytes := this.GetWriter().GetImportedObjects()
+	res, err := this.GetImportedObjectsUnorderedErr()
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Same as GetImportedObjectsUnordered, but returns an error instead of panicking
+func (this *Importer) GetImportedObjectsUnorderedErr() (res map[string][]byte, err error) {
+	_, writer, err := this.getSource()
+	if err != nil {
+		return nil, err
+	}
+
+	res = make(map[string][]byte, 0)
+	pdfObjIdBytes := writer.GetImportedObjects()
 	for pdfObjId, bytes := range pdfObjIdBytes {
 		res[pdfObjId.hash] = bytes
 	}
-	return res
+	return res, nil
 }
 
 // Get the positions of the hashes (sha1 - 40 characters) within each object, to be replaced with
 // actual objects ids by the pdf generator library
 func (this *Importer) GetImportedObjHashPos() map[string]map[int]string {
-	res := make(map[string]map[int]string, 0)
-	pdfObjIdPosHash := this.GetWriter().GetImportedObjHashPos()
+	res, err := this.GetImportedObjHashPosErr()
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Same as GetImportedObjHashPos, but returns an error instead of panicking
+func (this *Importer) GetImportedObjHashPosErr() (res map[string]map[int]string, err error) {
+	_, writer, err := this.getSource()
+	if err != nil {
+		return nil, err
+	}
+
+	res = make(map[string]map[int]string, 0)
+	pdfObjIdPosHash := writer.GetImportedObjHashPos()
 	for pdfObjId, posHashMap := range pdfObjIdPosHash {
 		res[pdfObjId.hash] = posHashMap
 	}
-	return res
+	return res, nil
 }
 
 // For a given template id (returned from ImportPage), get the template name (e.g. /GOFPDITPL1) and
 // the 4 float64 values necessary to draw the template a x,y for a given width and height.
 func (this *Importer) UseTemplate(tplid int, _x float64, _y float64, _w float64, _h float64) (string, float64, float64, float64, float64) {
+	tplName, scaleX, scaleY, tX, tY, err := this.UseTemplateErr(tplid, _x, _y, _w, _h)
+	if err != nil {
+		panic(err)
+	}
+	return tplName, scaleX, scaleY, tX, tY
+}
+
+// Same as UseTemplate, but returns an error instead of panicking
+func (this *Importer) UseTemplateErr(tplid int, _x float64, _y float64, _w float64, _h float64) (tplName string, scaleX float64, scaleY float64, tX float64, tY float64, err error) {
 	// Look up template id in importer tpl map
-	tplInfo := this.tplMap[tplid]
-	return tplInfo.Writer.UseTemplate(tplInfo.TemplateId, _x, _y, _w, _h)
+	tplInfo, ok := this.tplMap[tplid]
+	if !ok {
+		return "", 0, 0, 0, 0, errors.New(fmt.Sprintf("Template %d does not exist", tplid))
+	}
+
+	tplName, scaleX, scaleY, tX, tY = tplInfo.Writer.UseTemplate(tplInfo.TemplateId, _x, _y, _w, _h)
+	return tplName, scaleX, scaleY, tX, tY, nil
 }
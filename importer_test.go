@@ -0,0 +1,95 @@
+package gofpdi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestImporterWithoutSource(t *testing.T) {
+	importer := NewImporter()
+
+	if _, err := importer.GetNumPagesErr(); err == nil {
+		t.Error("GetNumPagesErr succeeded without a source")
+	}
+	if _, err := importer.ImportPageErr(1, "/MediaBox"); err == nil {
+		t.Error("ImportPageErr succeeded without a source")
+	}
+	if err := importer.SetNextObjectIDErr(10); err == nil {
+		t.Error("SetNextObjectIDErr succeeded without a source")
+	}
+	if _, err := importer.PutFormXobjectsErr(); err == nil {
+		t.Error("PutFormXobjectsErr succeeded without a source")
+	}
+	if _, err := importer.GetImportedObjectsErr(); err == nil {
+		t.Error("GetImportedObjectsErr succeeded without a source")
+	}
+	if _, err := importer.GetImportedObjectsUnorderedErr(); err == nil {
+		t.Error("GetImportedObjectsUnorderedErr succeeded without a source")
+	}
+	if _, err := importer.GetImportedObjHashPosErr(); err == nil {
+		t.Error("GetImportedObjHashPosErr succeeded without a source")
+	}
+	if _, _, _, _, _, err := importer.UseTemplateErr(0, 0, 0, 100, 100); err == nil {
+		t.Error("UseTemplateErr succeeded without a template")
+	}
+}
+
+func TestImporterImportPage(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/simple.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importer := NewImporter()
+	var rs io.ReadSeeker = bytes.NewReader(data)
+	if err := importer.SetSourceStreamErr(&rs); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := importer.GetNumPagesErr(); err != nil || n != 3 {
+		t.Fatalf("GetNumPagesErr = %d, %v, want 3", n, err)
+	}
+
+	if _, err := importer.ImportPageErr(4, "/MediaBox"); !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("ImportPageErr(4) = %v, want ErrPageOutOfRange", err)
+	}
+
+	tplid, err := importer.ImportPageErr(2, "/MediaBox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := importer.ImportPageErr(2, "/MediaBox"); err != nil || again != tplid {
+		t.Errorf("importing page 2 again = %d, %v, want %d", again, err, tplid)
+	}
+
+	if err := importer.SetNextObjectIDErr(10); err != nil {
+		t.Fatal(err)
+	}
+	xobjects, err := importer.PutFormXobjectsErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tplName, scaleX, scaleY, _, _, err := importer.UseTemplateErr(tplid, 0, 0, 306, 396)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := xobjects[tplName]; !ok {
+		t.Errorf("template %s was not written, got %v", tplName, xobjects)
+	}
+	if scaleX != 0.5 || scaleY != 0.5 {
+		t.Errorf("scale %v, %v, want 0.5", scaleX, scaleY)
+	}
+
+	objects, err := importer.GetImportedObjectsErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj, ok := objects[xobjects[tplName]]; !ok || !strings.Contains(obj, "/Subtype /Form") {
+		t.Errorf("form xobject %d not among the imported objects", xobjects[tplName])
+	}
+}
@@ -0,0 +1,221 @@
+package gofpdi
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ImpositionOptions configures Impose.  Sizes are in points (1/72 inch).
+type ImpositionOptions struct {
+	SheetWidth  float64
+	SheetHeight float64
+
+	// Grid of pages on each sheet, e.g. 2 x 1 for 2-up on a landscape sheet, 2 x 2 for 4-up or 3 x 3 for 9-up.
+	// Pages are placed from left to right, then from top to bottom.  Ignored for booklets.
+	Columns int
+	Rows    int
+
+	// Booklet lays out the pages for saddle stitching: two pages side by side on each side of a sheet, which is
+	// printed on both sides, folded in the middle and stitched.  Blank pages are added at the end so the page count
+	// is a multiple of 4.
+	Booklet bool
+
+	Margin float64 // space between the edges of the sheet and the pages
+	Gutter float64 // space between the cells of adjacent pages
+
+	// Rotate pages by 90 degrees where that makes them larger, e.g. landscape pages on a grid of portrait cells
+	Rotate bool
+
+	// Draw crop marks at the corners of each page, in the margins and gutters.  They are as long as the space
+	// around the page allows, up to 12 points.
+	CropMarks bool
+
+	// Page box of the source pages to place, "/TrimBox" if empty.  Crop marks are drawn at its edges.
+	Box string
+
+	// Pages to place, all pages if nil
+	Pages []PageRange
+}
+
+// Length of crop marks and their distance from the page
+const (
+	cropMarkLength = 12.0
+	cropMarkOffset = 3.0
+)
+
+// Impose places the pages of a document on sheets, n-up or as a booklet, and writes the sheets as a new document.
+// Each page is scaled to fit its cell, keeping its aspect ratio, and centered in it.
+func Impose(reader *PdfReader, out io.Writer, opts *ImpositionOptions) error {
+	if opts.SheetWidth <= 0 || opts.SheetHeight <= 0 {
+		return errors.New("Invalid sheet size")
+	}
+
+	columns, rows := opts.Columns, opts.Rows
+	if opts.Booklet {
+		columns, rows = 2, 1
+	}
+	if columns < 1 || rows < 1 {
+		return errors.New("Invalid number of columns or rows")
+	}
+
+	box := opts.Box
+	if box == "" {
+		box = "/TrimBox"
+	}
+
+	ranges := opts.Pages
+	if ranges == nil {
+		ranges = []PageRange{{From: 1}}
+	}
+	pagenos, err := pageRangeNumbers(ranges, reader.numPages())
+	if err != nil {
+		return err
+	}
+	if opts.Booklet {
+		pagenos = bookletOrder(pagenos)
+	}
+
+	cellWidth := (opts.SheetWidth - 2*opts.Margin - float64(columns-1)*opts.Gutter) / float64(columns)
+	cellHeight := (opts.SheetHeight - 2*opts.Margin - float64(rows-1)*opts.Gutter) / float64(rows)
+	if cellWidth <= 0 || cellHeight <= 0 {
+		return errors.New("Margins and gutters leave no space for the pages")
+	}
+
+	doc := NewDocument(out)
+
+	// Each page is imported once, even if it is placed several times
+	templates := make(map[int]int, 0)
+
+	perSheet := columns * rows
+	for i, pageno := range pagenos {
+		if i%perSheet == 0 {
+			if err := doc.AddPage(opts.SheetWidth, opts.SheetHeight); err != nil {
+				return err
+			}
+		}
+
+		// Blank page of a booklet
+		if pageno == 0 {
+			continue
+		}
+
+		tplid, ok := templates[pageno]
+		if !ok {
+			tplid, err = doc.ImportPage(reader, pageno, box)
+			if err != nil {
+				return err
+			}
+			templates[pageno] = tplid
+		}
+
+		w, h, err := doc.TemplateSize(tplid)
+		if err != nil {
+			return err
+		}
+
+		// Lower left corner of the cell
+		cell := i % perSheet
+		x := opts.Margin + float64(cell%columns)*(cellWidth+opts.Gutter)
+		y := opts.SheetHeight - opts.Margin - float64(cell/columns+1)*cellHeight - float64(cell/columns)*opts.Gutter
+
+		scale := math.Min(cellWidth/w, cellHeight/h)
+		rotated := opts.Rotate && math.Min(cellWidth/h, cellHeight/w) > scale
+		if rotated {
+			scale = math.Min(cellWidth/h, cellHeight/w)
+			w, h = h, w
+		}
+
+		// Lower left corner and size of the placed page
+		px := x + (cellWidth-w*scale)/2
+		py := y + (cellHeight-h*scale)/2
+		pw, ph := w*scale, h*scale
+
+		matrix := [6]float64{scale, 0, 0, scale, px, py}
+		if rotated {
+			// Rotated counterclockwise, so the bottom of the page faces right
+			matrix = [6]float64{0, scale, -scale, 0, px + pw, py}
+		}
+
+		if err := doc.drawTemplate(tplid, matrix); err != nil {
+			return err
+		}
+
+		if opts.CropMarks {
+			// Free space around the page: the rest of its cell, and the margin or half the gutter beyond it
+			column, row := cell%columns, cell/columns
+			space := [4]float64{
+				px - x + impositionSpace(column == 0, opts),
+				x + cellWidth - px - pw + impositionSpace(column == columns-1, opts),
+				py - y + impositionSpace(row == rows-1, opts),
+				y + cellHeight - py - ph + impositionSpace(row == 0, opts),
+			}
+			if err := doc.AddContent(cropMarks(px, py, pw, ph, space)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return doc.Close()
+}
+
+// Get the space beyond a side of a cell: the margin at the edge of the sheet, and half the gutter between cells
+func impositionSpace(outer bool, opts *ImpositionOptions) float64 {
+	if outer {
+		return opts.Margin
+	}
+	return opts.Gutter / 2
+}
+
+// Arrange pages for saddle stitching.  Each sheet holds, on its front, the last and the first remaining page and, on
+// its back, the second and the second to last.  0 stands for a blank page.
+func bookletOrder(pagenos []int) []int {
+	n := (len(pagenos) + 3) / 4 * 4
+
+	padded := make([]int, n)
+	copy(padded, pagenos)
+
+	result := make([]int, 0, n)
+	for i := 0; i < n/2; i += 2 {
+		result = append(result, padded[n-1-i], padded[i], padded[i+1], padded[n-2-i])
+	}
+
+	return result
+}
+
+// Build the content that draws crop marks at the corners of a placed page.  space holds the free space to the left,
+// right, bottom and top of the page; marks are shortened to fit into it, and left out where it is too small.
+func cropMarks(x, y, w, h float64, space [4]float64) string {
+	var content strings.Builder
+	content.WriteString("0 G 0.25 w\n")
+
+	line := func(x1, y1, x2, y2 float64) {
+		content.WriteString(fmt.Sprintf("%s %s m %s %s l S\n", formatNumber(x1), formatNumber(y1), formatNumber(x2), formatNumber(y2)))
+	}
+
+	// Each corner has a horizontal mark along its edge, pointing away from the page, and a vertical one
+	for _, corner := range [][4]float64{{x, y, -1, -1}, {x + w, y, 1, -1}, {x, y + h, -1, 1}, {x + w, y + h, 1, 1}} {
+		cx, cy, dx, dy := corner[0], corner[1], corner[2], corner[3]
+
+		horizontal := space[0]
+		if dx > 0 {
+			horizontal = space[1]
+		}
+		if length := math.Min(cropMarkLength, horizontal-cropMarkOffset); length > 0 {
+			line(cx+dx*cropMarkOffset, cy, cx+dx*(cropMarkOffset+length), cy)
+		}
+
+		vertical := space[2]
+		if dy > 0 {
+			vertical = space[3]
+		}
+		if length := math.Min(cropMarkLength, vertical-cropMarkOffset); length > 0 {
+			line(cx, cy+dy*cropMarkOffset, cx, cy+dy*(cropMarkOffset+length))
+		}
+	}
+
+	return content.String()
+}
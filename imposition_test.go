@@ -0,0 +1,119 @@
+package gofpdi
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestBookletOrder(t *testing.T) {
+	tests := []struct {
+		pagenos, want []int
+	}{
+		{[]int{1, 2, 3, 4}, []int{4, 1, 2, 3}},
+		{[]int{1, 2, 3, 4, 5, 6, 7, 8}, []int{8, 1, 2, 7, 6, 3, 4, 5}},
+		// Blank pages at the end
+		{[]int{1, 2, 3, 4, 5}, []int{0, 1, 2, 0, 0, 3, 4, 5}},
+		{[]int{7}, []int{0, 7, 0, 0}},
+		{[]int{}, []int{}},
+	}
+
+	for _, test := range tests {
+		if got := bookletOrder(test.pagenos); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("bookletOrder(%v) = %v, want %v", test.pagenos, got, test.want)
+		}
+	}
+}
+
+// Impose the pages of simple.pdf and get the matrices the templates are drawn with on each sheet
+func imposeTestFile(t *testing.T, opts *ImpositionOptions) [][]string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := Impose(readTestFile(t, "simple.pdf"), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewPdfReaderFromStream(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read the sheets: %v", err)
+	}
+
+	placement := regexp.MustCompile(`q ([^q]*) cm /\S+ Do Q`)
+	sheets := make([][]string, reader.NumPages())
+	for i := range sheets {
+		boxes, err := reader.PageBoxes(i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if boxes.MediaBox.Width() != opts.SheetWidth || boxes.MediaBox.Height() != opts.SheetHeight {
+			t.Errorf("sheet %d is %v", i+1, boxes.MediaBox)
+		}
+
+		content, err := reader.getContent(i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheets[i] = []string{}
+		for _, match := range placement.FindAllStringSubmatch(content, -1) {
+			sheets[i] = append(sheets[i], match[1])
+		}
+	}
+
+	return sheets
+}
+
+func TestImposeGrid(t *testing.T) {
+	// 4-up on a sheet of twice the size of the pages, from left to right, then from top to bottom
+	sheets := imposeTestFile(t, &ImpositionOptions{SheetWidth: 1224, SheetHeight: 1584, Columns: 2, Rows: 2})
+	want := [][]string{{"1 0 0 1 0 792", "1 0 0 1 612 792", "1 0 0 1 0 0"}}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("4-up placements %q, want %q", sheets, want)
+	}
+
+	// 2-up with a margin and a gutter: the cells are 300 x 592, the pages are scaled to their width and centered
+	sheets = imposeTestFile(t, &ImpositionOptions{SheetWidth: 640, SheetHeight: 612, Columns: 2, Rows: 1, Margin: 10, Gutter: 20})
+	scale := formatNumber(300.0 / 612)
+	y := formatNumber(10 + (592-792*300.0/612)/2)
+	want = [][]string{
+		{scale + " 0 0 " + scale + " 10 " + y, scale + " 0 0 " + scale + " 330 " + y},
+		{scale + " 0 0 " + scale + " 10 " + y},
+	}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("2-up placements %q, want %q", sheets, want)
+	}
+
+	// Portrait pages turned on landscape sheets
+	sheets = imposeTestFile(t, &ImpositionOptions{SheetWidth: 792, SheetHeight: 612, Columns: 1, Rows: 1, Rotate: true,
+		Pages: []PageRange{{From: 2, To: 2}}})
+	want = [][]string{{"0 1 -1 0 792 0"}}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("rotated placements %q, want %q", sheets, want)
+	}
+}
+
+func TestImposeBooklet(t *testing.T) {
+	// Pages 1 to 3 and a blank page on the front and back of one sheet
+	sheets := imposeTestFile(t, &ImpositionOptions{SheetWidth: 1224, SheetHeight: 792, Booklet: true, Columns: 5})
+	want := [][]string{{"1 0 0 1 612 0"}, {"1 0 0 1 0 0", "1 0 0 1 612 0"}}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("booklet placements %q, want %q", sheets, want)
+	}
+}
+
+func TestImposeOptions(t *testing.T) {
+	reader := readTestFile(t, "simple.pdf")
+
+	for _, opts := range []*ImpositionOptions{
+		{SheetWidth: 0, SheetHeight: 792, Columns: 1, Rows: 1},
+		{SheetWidth: 612, SheetHeight: 792, Columns: 0, Rows: 1},
+		{SheetWidth: 612, SheetHeight: 792, Columns: 1, Rows: 1, Margin: 400},
+		{SheetWidth: 612, SheetHeight: 792, Columns: 1, Rows: 1, Pages: []PageRange{{From: 4, To: 4}}},
+	} {
+		var buf bytes.Buffer
+		if err := Impose(reader, &buf, opts); err == nil {
+			t.Errorf("options %+v accepted", *opts)
+		}
+	}
+}
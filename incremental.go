@@ -0,0 +1,316 @@
+package gofpdi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// IncrementalUpdate collects changed and new objects of a document and appends them to the original file as an
+// incremental update (PDF 32000-1:2008, 7.5.6): the objects, a cross-reference section listing only them, and a
+// trailer whose /Prev points to the previous cross-reference section.  The original bytes are left untouched.
+type IncrementalUpdate struct {
+	reader  *PdfReader
+	objects map[int]*PdfValue
+	gens    map[int]int
+	nextId  int
+}
+
+// NewIncrementalUpdate starts an incremental update of the document read by reader.  Encrypted documents and
+// documents whose xref table had to be rebuilt (see WithXrefRepair) cannot be updated.
+func NewIncrementalUpdate(reader *PdfReader) (*IncrementalUpdate, error) {
+	if reader.trailer.Key("/Encrypt") != nil {
+		return nil, errors.Wrap(ErrUnsupportedEncryption, "Cannot update an encrypted document")
+	}
+	if reader.startXref < 0 {
+		return nil, errors.Wrap(ErrCorruptXref, "Cannot update a document whose xref table was rebuilt")
+	}
+
+	nextId, _ := reader.trailer.Key("/Size").AsInt()
+	for _, ref := range reader.ObjectRefs() {
+		if ref.Id >= nextId {
+			nextId = ref.Id + 1
+		}
+	}
+	if nextId < 1 {
+		nextId = 1
+	}
+
+	return &IncrementalUpdate{
+		reader:  reader,
+		objects: make(map[int]*PdfValue, 0),
+		gens:    make(map[int]int, 0),
+		nextId:  nextId,
+	}, nil
+}
+
+// EditObject returns a copy of an indirect object that is written with the update, so changes made to it replace
+// the original object.  Like the values returned by PdfReader.Object, the copy is a PDF_TYPE_OBJECT (or
+// PDF_TYPE_STREAM) value whose Value holds the object.  Editing the same object again returns the same copy.
+func (this *IncrementalUpdate) EditObject(id, gen int) (*PdfValue, error) {
+	if obj, ok := this.objects[id]; ok && this.gens[id] == gen {
+		return obj, nil
+	}
+
+	obj, err := this.reader.Object(id, gen)
+	if err != nil {
+		return nil, err
+	}
+
+	obj = copyPdfValue(obj)
+	this.objects[id] = obj
+	this.gens[id] = gen
+
+	return obj, nil
+}
+
+// Get an indirect object as it will be after the update
+func (this *IncrementalUpdate) object(id, gen int) (*PdfValue, error) {
+	if obj, ok := this.objects[id]; ok && this.gens[id] == gen {
+		return obj, nil
+	}
+
+	return this.reader.Object(id, gen)
+}
+
+// SetObject replaces an indirect object with a new value
+func (this *IncrementalUpdate) SetObject(id, gen int, value *PdfValue) {
+	this.objects[id] = value
+	this.gens[id] = gen
+}
+
+// AddObject adds a new indirect object and returns a reference to it.  Streams are added as a PDF_TYPE_STREAM
+// value whose Value holds the stream dictionary and whose Stream holds the (encoded) data.
+func (this *IncrementalUpdate) AddObject(value *PdfValue) *PdfValue {
+	id := this.nextId
+	this.nextId++
+
+	this.objects[id] = value
+	this.gens[id] = 0
+
+	return &PdfValue{Type: PDF_TYPE_OBJREF, Id: id, Gen: 0}
+}
+
+// Copy a value of another document into the update, adding the objects it references (and the objects they
+// reference) as new objects.  imported maps the ids of the objects of reader added so far to their references, so
+// objects shared by several values are added once.  Values of the updated document itself are returned as they are.
+func (this *IncrementalUpdate) importValue(reader *PdfReader, value *PdfValue, imported map[int]*PdfValue) (*PdfValue, error) {
+	if reader == this.reader || value == nil {
+		return value, nil
+	}
+
+	switch value.Type {
+	case PDF_TYPE_OBJREF:
+		if ref, ok := imported[value.Id]; ok {
+			return ref, nil
+		}
+
+		// References to objects that do not exist are references to the null object
+		obj, err := reader.Object(value.Id, value.Gen)
+		if errors.Is(err, ErrObjectNotFound) {
+			return &PdfValue{Type: PDF_TYPE_NULL}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Add the reference before copying the object, so references back to it are resolved
+		ref := this.AddObject(&PdfValue{Type: PDF_TYPE_NULL})
+		imported[value.Id] = ref
+
+		copied, err := this.importValue(reader, obj, imported)
+		if err != nil {
+			return nil, err
+		}
+		this.SetObject(ref.Id, ref.Gen, copied)
+
+		return ref, nil
+
+	case PDF_TYPE_OBJECT, PDF_TYPE_STREAM:
+		inner, err := this.importValue(reader, value.Value, imported)
+		if err != nil {
+			return nil, err
+		}
+		return &PdfValue{Type: value.Type, Id: value.Id, Gen: value.Gen, Value: inner, Stream: value.Stream}, nil
+
+	case PDF_TYPE_ARRAY:
+		array := make([]*PdfValue, len(value.Array))
+		for i, v := range value.Array {
+			copied, err := this.importValue(reader, v, imported)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = copied
+		}
+		return &PdfValue{Type: PDF_TYPE_ARRAY, Array: array}, nil
+
+	case PDF_TYPE_DICTIONARY:
+		dict := make(map[string]*PdfValue, len(value.Dictionary))
+		for k, v := range value.Dictionary {
+			copied, err := this.importValue(reader, v, imported)
+			if err != nil {
+				return nil, err
+			}
+			dict[k] = copied
+		}
+		return &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: dict}, nil
+	}
+
+	return value, nil
+}
+
+// Write writes the original document followed by the update
+func (this *IncrementalUpdate) Write(w io.Writer) error {
+	_, err := io.Copy(w, io.NewSectionReader(this.reader.f, 0, this.reader.nBytes))
+	if err != nil {
+		return errors.Wrap(err, "Failed to copy original document")
+	}
+
+	return this.WriteUpdate(w)
+}
+
+// WriteUpdate writes only the update, which must be appended to the original document
+func (this *IncrementalUpdate) WriteUpdate(w io.Writer) error {
+	var buf bytes.Buffer
+	offset := int(this.reader.nBytes)
+
+	// The update has to start on a new line
+	if this.reader.nBytes > 0 {
+		last := make([]byte, 1)
+		if _, err := this.reader.f.ReadAt(last, this.reader.nBytes-1); err != nil {
+			return errors.Wrap(err, "Failed to read original document")
+		}
+		if last[0] != '\n' && last[0] != '\r' {
+			buf.WriteString("\n")
+		}
+	}
+
+	ids := make([]int, 0, len(this.objects))
+	for id := range this.objects {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	writer := &PdfWriter{keep_obj_ids: true, current_obj: &PdfObject{buffer: &buf}}
+	offsets := make(map[int]int, len(ids))
+
+	for _, id := range ids {
+		offsets[id] = offset + buf.Len()
+
+		value := this.objects[id]
+		if value.Type == PDF_TYPE_OBJECT {
+			value = value.Value
+		}
+
+		writer.out(fmt.Sprintf("%d %d obj", id, this.gens[id]))
+		writer.writeValue(value)
+		writer.out("")
+		writer.out("endobj")
+	}
+
+	// Cross-reference section, in the same format as the one it follows
+	if typ, _ := this.reader.trailer.Key("/Type").AsName(); typ == "/XRef" {
+		this.writeXrefStream(writer, ids, offsets, offset+buf.Len())
+	} else {
+		this.writeXrefTable(writer, ids, offsets, offset+buf.Len())
+	}
+
+	_, err := w.Write(buf.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "Failed to write incremental update")
+	}
+
+	return nil
+}
+
+// Write a cross-reference table and trailer
+func (this *IncrementalUpdate) writeXrefTable(writer *PdfWriter, ids []int, offsets map[int]int, xrefPos int) {
+	writer.out("xref")
+	for start := 0; start < len(ids); {
+		end := start + 1
+		for end < len(ids) && ids[end] == ids[end-1]+1 {
+			end++
+		}
+
+		writer.out(fmt.Sprintf("%d %d", ids[start], end-start))
+		for _, id := range ids[start:end] {
+			writer.straightOut(fmt.Sprintf("%010d %05d n\r\n", offsets[id], this.gens[id]))
+		}
+		start = end
+	}
+
+	writer.out("trailer")
+	writer.writeValue(this.trailer(this.nextId))
+	writer.out("")
+	writer.out("startxref")
+	writer.out(fmt.Sprintf("%d", xrefPos))
+	writer.out("%%EOF")
+}
+
+// Write a cross-reference stream, which also serves as the trailer
+func (this *IncrementalUpdate) writeXrefStream(writer *PdfWriter, ids []int, offsets map[int]int, xrefPos int) {
+	// The stream is an object of the update itself
+	streamId := this.nextId
+	ids = append(ids, streamId)
+	offsets[streamId] = xrefPos
+
+	// Use 8 bytes for the offsets of files larger than 4 GB
+	offsetWidth := 4
+	if int64(xrefPos) > 0xffffffff {
+		offsetWidth = 8
+	}
+
+	var data bytes.Buffer
+	index := make([]*PdfValue, 0)
+	for start := 0; start < len(ids); {
+		end := start + 1
+		for end < len(ids) && ids[end] == ids[end-1]+1 {
+			end++
+		}
+
+		index = append(index, &PdfValue{Type: PDF_TYPE_NUMERIC, Int: ids[start]}, &PdfValue{Type: PDF_TYPE_NUMERIC, Int: end - start})
+		for _, id := range ids[start:end] {
+			data.WriteByte(1)
+			for i := offsetWidth - 1; i >= 0; i-- {
+				data.WriteByte(byte(offsets[id] >> uint(8*i)))
+			}
+			data.WriteByte(byte(this.gens[id] >> 8))
+			data.WriteByte(byte(this.gens[id]))
+		}
+		start = end
+	}
+
+	dict := this.trailer(streamId + 1)
+	dict.Dictionary["/Type"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: "/XRef"}
+	dict.Dictionary["/Index"] = &PdfValue{Type: PDF_TYPE_ARRAY, Array: index}
+	dict.Dictionary["/W"] = &PdfValue{Type: PDF_TYPE_ARRAY, Array: []*PdfValue{
+		{Type: PDF_TYPE_NUMERIC, Int: 1},
+		{Type: PDF_TYPE_NUMERIC, Int: offsetWidth},
+		{Type: PDF_TYPE_NUMERIC, Int: 2},
+	}}
+
+	writer.out(fmt.Sprintf("%d 0 obj", streamId))
+	writer.writeValue(&PdfValue{Type: PDF_TYPE_STREAM, Value: dict, Stream: &PdfValue{Type: PDF_TYPE_STREAM, Bytes: data.Bytes()}})
+	writer.out("endobj")
+	writer.out("startxref")
+	writer.out(fmt.Sprintf("%d", xrefPos))
+	writer.out("%%EOF")
+}
+
+// Build the trailer dictionary of the update
+func (this *IncrementalUpdate) trailer(size int) *PdfValue {
+	dict := &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: make(map[string]*PdfValue, 0)}
+
+	dict.Dictionary["/Size"] = &PdfValue{Type: PDF_TYPE_NUMERIC, Int: size}
+	dict.Dictionary["/Prev"] = &PdfValue{Type: PDF_TYPE_NUMERIC, Int: this.reader.startXref}
+	for _, key := range []string{"/Root", "/Info", "/ID"} {
+		if value := this.reader.trailer.Key(key); value != nil {
+			dict.Dictionary[key] = value
+		}
+	}
+
+	return dict
+}
@@ -0,0 +1,78 @@
+package gofpdi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIncrementalUpdate(t *testing.T) {
+	tests := []struct {
+		file       string
+		xrefStream bool
+	}{
+		{"simple.pdf", false},
+		{"xref-stream.pdf", true},
+	}
+
+	for _, test := range tests {
+		reader := readTestFile(t, test.file)
+		root := reader.Trailer().Key("/Root")
+		size, _ := reader.Trailer().Key("/Size").AsInt()
+
+		update, err := NewIncrementalUpdate(reader)
+		if err != nil {
+			t.Fatalf("%s: %v", test.file, err)
+		}
+
+		catalog, err := update.EditObject(root.Id, root.Gen)
+		if err != nil {
+			t.Fatalf("%s: %v", test.file, err)
+		}
+		dict, ok := catalog.AsDict()
+		if !ok {
+			t.Fatalf("%s: catalog is not a dictionary", test.file)
+		}
+		dict["/Added"] = update.AddObject(&PdfValue{Type: PDF_TYPE_STRING, String: "Added"})
+
+		var buf bytes.Buffer
+		if err := update.Write(&buf); err != nil {
+			t.Fatalf("%s: %v", test.file, err)
+		}
+
+		updated, err := NewPdfReaderFromStream(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to read update: %v", test.file, err)
+		}
+		checkPages(t, test.file, updated, 1, 2, 3)
+
+		trailer := updated.Trailer()
+		if trailer.Key("/Prev") == nil {
+			t.Errorf("%s: trailer has no /Prev", test.file)
+		}
+		if name, _ := trailer.Key("/Type").AsName(); (name == "/XRef") != test.xrefStream {
+			t.Errorf("%s: trailer /Type %q, want an xref stream: %v", test.file, name, test.xrefStream)
+		}
+		// The added object, and the xref stream itself
+		want := size + 1
+		if test.xrefStream {
+			want++
+		}
+		if got, _ := trailer.Key("/Size").AsInt(); got != want {
+			t.Errorf("%s: /Size %d, want %d", test.file, got, want)
+		}
+
+		added, err := updated.Resolve(updated.Catalog().Key("/Added"))
+		if err != nil {
+			t.Fatalf("%s: %v", test.file, err)
+		}
+		if got := added.direct().String; got != "Added" {
+			t.Errorf("%s: added object %q, want %q", test.file, got, "Added")
+		}
+
+		for _, ref := range updated.ObjectRefs() {
+			if _, err := updated.Resolve(ref); err != nil {
+				t.Errorf("%s: object %d: %v", test.file, ref.Id, err)
+			}
+		}
+	}
+}
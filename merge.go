@@ -0,0 +1,118 @@
+package gofpdi
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PageRange is a range of page numbers.  To is 0 for a range that ends at the last page, and may be less than From
+// for pages in reverse order.
+type PageRange struct {
+	From int
+	To   int
+}
+
+// ParsePageRanges parses page ranges such as "1-3,7,10-": single pages, ranges of pages and ranges that end at the
+// last page, separated by commas
+func ParsePageRanges(s string) ([]PageRange, error) {
+	ranges := make([]PageRange, 0)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			from, to = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+
+		var r PageRange
+		var err error
+		if r.From, err = strconv.Atoi(from); err != nil || r.From < 1 {
+			return nil, errors.New("Invalid page range: " + part)
+		}
+		if to != "" {
+			if r.To, err = strconv.Atoi(to); err != nil || r.To < 1 {
+				return nil, errors.New("Invalid page range: " + part)
+			}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges, nil
+}
+
+// Get the page numbers of page ranges in a document with the given number of pages
+func pageRangeNumbers(ranges []PageRange, numPages int) ([]int, error) {
+	pagenos := make([]int, 0)
+
+	for _, r := range ranges {
+		to := r.To
+		if to == 0 {
+			to = numPages
+		}
+		if r.From < 1 || r.From > numPages || to > numPages {
+			return nil, errors.Wrap(ErrPageOutOfRange, fmt.Sprintf("Pages %d-%d of %d", r.From, to, numPages))
+		}
+
+		step := 1
+		if to < r.From {
+			step = -1
+		}
+		for pageno := r.From; pageno != to+step; pageno += step {
+			pagenos = append(pagenos, pageno)
+		}
+	}
+
+	return pagenos, nil
+}
+
+// MergeOptions configures Merge
+type MergeOptions struct {
+	// Pages of each input to copy, by the index of the input.  All pages are copied of inputs without ranges.
+	Pages map[int][]PageRange
+
+	// Options of the readers of the inputs, e.g. WithPassword or WithXrefRepair
+	ReaderOptions []ReaderOption
+}
+
+// Merge copies the pages of several PDF documents, in order, into a new document written to out.  Pages are copied
+// as they are (see Document.CopyPages), and objects shared by the pages of an input are written once.  opts may be
+// nil.
+func Merge(inputs []io.ReadSeeker, out io.Writer, opts *MergeOptions) error {
+	if opts == nil {
+		opts = &MergeOptions{}
+	}
+
+	doc := NewDocument(out)
+
+	for i, input := range inputs {
+		reader, err := NewPdfReaderFromStream(input, opts.ReaderOptions...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read input %d", i)
+		}
+
+		ranges, ok := opts.Pages[i]
+		if !ok {
+			ranges = []PageRange{{From: 1}}
+		}
+
+		pagenos, err := pageRangeNumbers(ranges, reader.numPages())
+		if err != nil {
+			return errors.Wrapf(err, "Invalid pages of input %d", i)
+		}
+
+		if err := doc.CopyPages(reader, pagenos...); err != nil {
+			return errors.Wrapf(err, "Failed to copy pages of input %d", i)
+		}
+	}
+
+	return doc.Close()
+}
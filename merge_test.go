@@ -0,0 +1,50 @@
+package gofpdi
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParsePageRanges(t *testing.T) {
+	ranges, err := ParsePageRanges("1-3, 7,10-,5-4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PageRange{{From: 1, To: 3}, {From: 7, To: 7}, {From: 10}, {From: 5, To: 4}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ParsePageRanges = %v, want %v", ranges, want)
+	}
+
+	for _, s := range []string{"0", "a-3", "2-x", "-3"} {
+		if _, err := ParsePageRanges(s); err == nil {
+			t.Errorf("ParsePageRanges(%q) accepted an invalid range", s)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var inputs []io.ReadSeeker
+	for _, file := range []string{"simple.pdf", "xref-stream.pdf"} {
+		data, err := ioutil.ReadFile("testdata/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, bytes.NewReader(data))
+	}
+
+	var buf bytes.Buffer
+	opts := &MergeOptions{Pages: map[int][]PageRange{1: {{From: 3, To: 2}}}}
+	if err := Merge(inputs, &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewPdfReaderFromStream(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read merged document: %v", err)
+	}
+	checkPages(t, "merged", reader, 1, 2, 3, 3, 2)
+}
@@ -0,0 +1,328 @@
+package gofpdi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Metadata holds the document information dictionary (/Info in the trailer) and the XMP metadata packet
+// (/Metadata in the catalog) of a document.  Fields are empty (or zero) if the document does not define them.
+type Metadata struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string
+	Producer     string
+	CreationDate time.Time
+	ModDate      time.Time
+	Trapped      string            // /True, /False or /Unknown
+	Custom       map[string]string // other text entries of the information dictionary, keyed without the slash
+
+	XMP       []byte       // raw XMP packet
+	XMPParsed *XMPMetadata // parsed XMP packet, nil if there is none or it is not well-formed
+}
+
+// XMPMetadata holds the commonly used properties of an XMP packet
+type XMPMetadata struct {
+	Title        string
+	Creators     []string
+	Description  string
+	Subjects     []string
+	CreatorTool  string
+	Producer     string
+	Keywords     string
+	CreateDate   time.Time
+	ModifyDate   time.Time
+	MetadataDate time.Time
+	DocumentID   string
+	InstanceID   string
+
+	// All simple, array and language alternative properties, keyed by prefix and name (e.g. "dc:creator").
+	// Properties of well-known namespaces use their usual prefix, others are keyed by namespace URI and name.
+	Properties map[string][]string
+}
+
+// Usual prefixes of well-known XMP namespaces
+var xmpPrefixes = map[string]string{
+	"http://purl.org/dc/elements/1.1/":            "dc",
+	"http://ns.adobe.com/xap/1.0/":                "xmp",
+	"http://ns.adobe.com/xap/1.0/mm/":             "xmpMM",
+	"http://ns.adobe.com/xap/1.0/rights/":         "xmpRights",
+	"http://ns.adobe.com/pdf/1.3/":                "pdf",
+	"http://ns.adobe.com/photoshop/1.0/":          "photoshop",
+	"http://www.aiim.org/pdfa/ns/id/":             "pdfaid",
+	"http://www.aiim.org/pdfua/ns/id/":            "pdfuaid",
+	"http://www.w3.org/1999/02/22-rdf-syntax-ns#": "rdf",
+}
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// Metadata returns the document information dictionary and the XMP metadata of the document
+func (this *PdfReader) Metadata() (*Metadata, error) {
+	meta := &Metadata{Custom: make(map[string]string, 0)}
+
+	if infoSpec, ok := this.trailer.Dictionary["/Info"]; ok {
+		info, err := this.Resolve(infoSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve info dictionary")
+		}
+
+		dict, _ := info.AsDict()
+		for key, value := range dict {
+			value, err := this.Resolve(value)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to resolve info entry "+key)
+			}
+
+			if key == "/Trapped" {
+				if name, ok := value.AsName(); ok {
+					meta.Trapped = name
+				}
+				continue
+			}
+
+			text, ok := value.AsText()
+			if !ok {
+				continue
+			}
+
+			switch key {
+			case "/Title":
+				meta.Title = text
+			case "/Author":
+				meta.Author = text
+			case "/Subject":
+				meta.Subject = text
+			case "/Keywords":
+				meta.Keywords = text
+			case "/Creator":
+				meta.Creator = text
+			case "/Producer":
+				meta.Producer = text
+			case "/CreationDate":
+				meta.CreationDate, _ = ParsePdfDate(text)
+			case "/ModDate":
+				meta.ModDate, _ = ParsePdfDate(text)
+			default:
+				meta.Custom[strings.TrimPrefix(key, "/")] = text
+			}
+		}
+	}
+
+	if metadataSpec := this.catalog.Key("/Metadata"); metadataSpec != nil {
+		xmp, err := this.DecodeStream(metadataSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode metadata stream")
+		}
+
+		meta.XMP = xmp
+		meta.XMPParsed, _ = ParseXMP(xmp)
+	}
+
+	return meta, nil
+}
+
+// Metadata is the same as PdfReader.Metadata
+func (e *Exporter) Metadata() (*Metadata, error) {
+	return e.reader.Metadata()
+}
+
+// D:YYYYMMDDHHmmSSOHH'mm' where everything after the year is optional
+var pdfDateRegexp = regexp.MustCompile(`^(?:D:)?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?(?:([Zz+\-])(?:(\d{2})'?(?:(\d{2})'?)?)?)?`)
+
+// ParsePdfDate parses a PDF date string such as "D:20230102150405+01'00'".  Missing fields default to their
+// lowest value, and a date without a time zone is taken to be in UTC.
+func ParsePdfDate(s string) (time.Time, error) {
+	m := pdfDateRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return time.Time{}, errors.New("Invalid date: " + s)
+	}
+
+	field := func(i, def int) int {
+		if m[i] == "" {
+			return def
+		}
+		n, _ := strconv.Atoi(m[i])
+		return n
+	}
+
+	month := field(2, 1)
+	day := field(3, 1)
+	hour := field(4, 0)
+	minute := field(5, 0)
+	second := field(6, 0)
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, errors.New("Invalid date: " + s)
+	}
+
+	loc := time.UTC
+	if m[7] == "+" || m[7] == "-" {
+		offset := field(8, 0)*3600 + field(9, 0)*60
+		if m[7] == "-" {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+
+	return time.Date(field(1, 0), time.Month(month), day, hour, minute, second, 0, loc), nil
+}
+
+// Layouts of the dates used in XMP (a subset of ISO 8601)
+var xmpDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// ParseXMPDate parses an XMP date such as "2023-01-02T15:04:05+01:00".  A date without a time zone is taken
+// to be in UTC.
+func ParseXMPDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range xmpDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errors.New("Invalid date: " + s)
+}
+
+// ParseXMP parses an XMP packet and extracts its properties
+func ParseXMP(data []byte) (*XMPMetadata, error) {
+	properties := make(map[string][]string, 0)
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	// Properties are attributes or child elements of the top level rdf:Description elements.  Array values are
+	// rdf:li elements of an rdf:Alt, rdf:Bag or rdf:Seq container.  Structured values are skipped.
+	depth := 0
+	descriptionDepth := 0
+	property := ""
+	var values []string
+	var text strings.Builder
+	simple := true
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse XMP packet")
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+
+			switch {
+			case property == "" && descriptionDepth == 0 && isRdfElement(t.Name, "Description"):
+				descriptionDepth = depth
+				for _, attr := range t.Attr {
+					if attr.Name.Space == "" || attr.Name.Space == rdfNamespace || attr.Name.Space == "xmlns" {
+						continue
+					}
+					key := xmpKey(attr.Name)
+					properties[key] = append(properties[key], attr.Value)
+				}
+			case property == "" && descriptionDepth != 0 && depth == descriptionDepth+1:
+				property = xmpKey(t.Name)
+				values = nil
+				simple = true
+				text.Reset()
+				for _, attr := range t.Attr {
+					if isRdfElement(attr.Name, "resource") {
+						values = append(values, attr.Value)
+					}
+				}
+			case property != "" && depth == descriptionDepth+3 && isRdfElement(t.Name, "li"):
+				simple = true
+				text.Reset()
+			case property != "" && !(depth == descriptionDepth+2 && t.Name.Space == rdfNamespace):
+				simple = false
+			}
+
+		case xml.CharData:
+			if property != "" {
+				text.Write(t)
+			}
+
+		case xml.EndElement:
+			switch {
+			case property != "" && depth == descriptionDepth+3 && isRdfElement(t.Name, "li"):
+				if simple {
+					values = append(values, strings.TrimSpace(text.String()))
+				}
+				text.Reset()
+			case property != "" && depth == descriptionDepth+1:
+				if values == nil && simple {
+					if s := strings.TrimSpace(text.String()); s != "" {
+						values = []string{s}
+					}
+				}
+				if len(values) > 0 {
+					properties[property] = append(properties[property], values...)
+				}
+				property = ""
+			case depth == descriptionDepth:
+				descriptionDepth = 0
+			}
+
+			depth--
+		}
+	}
+
+	first := func(key string) string {
+		if v := properties[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	date := func(key string) time.Time {
+		t, _ := ParseXMPDate(first(key))
+		return t
+	}
+
+	return &XMPMetadata{
+		Title:        first("dc:title"),
+		Creators:     properties["dc:creator"],
+		Description:  first("dc:description"),
+		Subjects:     properties["dc:subject"],
+		CreatorTool:  first("xmp:CreatorTool"),
+		Producer:     first("pdf:Producer"),
+		Keywords:     first("pdf:Keywords"),
+		CreateDate:   date("xmp:CreateDate"),
+		ModifyDate:   date("xmp:ModifyDate"),
+		MetadataDate: date("xmp:MetadataDate"),
+		DocumentID:   first("xmpMM:DocumentID"),
+		InstanceID:   first("xmpMM:InstanceID"),
+		Properties:   properties,
+	}, nil
+}
+
+// Check whether an XML name is an element or attribute of the RDF namespace
+func isRdfElement(name xml.Name, local string) bool {
+	return name.Space == rdfNamespace && name.Local == local
+}
+
+// Get the key of an XMP property: its usual prefix and name, or its namespace URI and name
+func xmpKey(name xml.Name) string {
+	if prefix, ok := xmpPrefixes[name.Space]; ok {
+		return prefix + ":" + name.Local
+	}
+	return name.Space + name.Local
+}
@@ -0,0 +1,69 @@
+package gofpdi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	reader := readTestFile(t, "metadata.pdf")
+
+	meta, err := reader.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The title is UTF-16BE, the author PDFDocEncoding with an em dash and a ligature
+	if meta.Title != "Café – 日本" || meta.Author != "José — ﬁx" || meta.Producer != "prod" {
+		t.Errorf("title %q, author %q, producer %q", meta.Title, meta.Author, meta.Producer)
+	}
+	if meta.Trapped != "/False" || meta.Custom["Department"] != "Sales" {
+		t.Errorf("trapped %q, custom %v", meta.Trapped, meta.Custom)
+	}
+	if want := time.Date(2023, 1, 2, 15, 4, 5, 0, time.FixedZone("", 90*60)); !meta.CreationDate.Equal(want) {
+		t.Errorf("creation date %v, want %v", meta.CreationDate, want)
+	}
+
+	xmp := meta.XMPParsed
+	if xmp == nil {
+		t.Fatal("XMP packet was not parsed")
+	}
+	if xmp.Title != "XMP Title" || xmp.CreatorTool != "Writer 7" || xmp.Producer != "XMP Producer" || xmp.DocumentID != "uuid:1234" {
+		t.Errorf("XMP %+v", xmp)
+	}
+	if !reflect.DeepEqual(xmp.Creators, []string{"Alice", "Bob"}) || !reflect.DeepEqual(xmp.Subjects, []string{"one", "two"}) {
+		t.Errorf("XMP creators %v, subjects %v", xmp.Creators, xmp.Subjects)
+	}
+	if want := time.Date(2023, 2, 3, 10, 0, 0, 0, time.UTC); !xmp.ModifyDate.Equal(want) {
+		t.Errorf("XMP modify date %v, want %v", xmp.ModifyDate, want)
+	}
+}
+
+func TestParsePdfDate(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"D:20230102150405+01'00'", time.Date(2023, 1, 2, 15, 4, 5, 0, time.FixedZone("", 3600))},
+		{"D:20230102150405-05'30", time.Date(2023, 1, 2, 15, 4, 5, 0, time.FixedZone("", -(5*3600+30*60)))},
+		{"D:20230102150405Z", time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"D:202301", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2023", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		got, err := ParsePdfDate(test.s)
+		if err != nil {
+			t.Errorf("ParsePdfDate(%q): %v", test.s, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("ParsePdfDate(%q) = %v, want %v", test.s, got, test.want)
+		}
+	}
+
+	if _, err := ParsePdfDate("D:yesterday"); err == nil {
+		t.Error("ParsePdfDate accepted an invalid date")
+	}
+}
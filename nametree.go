@@ -0,0 +1,135 @@
+package gofpdi
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// Look up a key in a name tree (PDF 32000-1:2008, 7.9.6).  Returns nil if the tree does not contain the key.
+// Subtrees are skipped using their /Limits, or searched if they have none.
+func (this *PdfReader) lookupNameTree(root *PdfValue, key []byte) (*PdfValue, error) {
+	visited := make(map[int]bool, 0)
+
+	var lookup func(nodeSpec *PdfValue) (*PdfValue, error)
+	lookup = func(nodeSpec *PdfValue) (*PdfValue, error) {
+		if nodeSpec.IsRef() {
+			if visited[nodeSpec.Id] {
+				return nil, nil
+			}
+			visited[nodeSpec.Id] = true
+		}
+
+		node, err := this.Resolve(nodeSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve name tree node")
+		}
+
+		if limits, ok := node.Key("/Limits").AsArray(); ok && len(limits) == 2 {
+			first, _ := limits[0].AsBytes()
+			last, _ := limits[1].AsBytes()
+			if bytes.Compare(key, first) < 0 || bytes.Compare(key, last) > 0 {
+				return nil, nil
+			}
+		}
+
+		names, _ := node.Key("/Names").AsArray()
+		for i := 0; i+1 < len(names); i += 2 {
+			if name, ok := names[i].AsBytes(); ok && bytes.Equal(name, key) {
+				return names[i+1], nil
+			}
+		}
+
+		kids, _ := node.Key("/Kids").AsArray()
+		for _, kid := range kids {
+			value, err := lookup(kid)
+			if err != nil || value != nil {
+				return value, err
+			}
+		}
+
+		return nil, nil
+	}
+
+	return lookup(root)
+}
+
+// Call fn for every entry of a name tree, in the order of the tree
+func (this *PdfReader) walkNameTree(root *PdfValue, fn func(key []byte, value *PdfValue) error) error {
+	visited := make(map[int]bool, 0)
+
+	var walk func(nodeSpec *PdfValue) error
+	walk = func(nodeSpec *PdfValue) error {
+		if nodeSpec.IsRef() {
+			if visited[nodeSpec.Id] {
+				return nil
+			}
+			visited[nodeSpec.Id] = true
+		}
+
+		node, err := this.Resolve(nodeSpec)
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve name tree node")
+		}
+
+		names, _ := node.Key("/Names").AsArray()
+		for i := 0; i+1 < len(names); i += 2 {
+			if name, ok := names[i].AsBytes(); ok {
+				if err := fn(name, names[i+1]); err != nil {
+					return err
+				}
+			}
+		}
+
+		kids, _ := node.Key("/Kids").AsArray()
+		for _, kid := range kids {
+			if err := walk(kid); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return walk(root)
+}
+
+// Call fn for every entry of a number tree (PDF 32000-1:2008, 7.9.7), in the order of the tree
+func (this *PdfReader) walkNumberTree(root *PdfValue, fn func(key int, value *PdfValue) error) error {
+	visited := make(map[int]bool, 0)
+
+	var walk func(nodeSpec *PdfValue) error
+	walk = func(nodeSpec *PdfValue) error {
+		if nodeSpec.IsRef() {
+			if visited[nodeSpec.Id] {
+				return nil
+			}
+			visited[nodeSpec.Id] = true
+		}
+
+		node, err := this.Resolve(nodeSpec)
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve number tree node")
+		}
+
+		nums, _ := node.Key("/Nums").AsArray()
+		for i := 0; i+1 < len(nums); i += 2 {
+			if key, ok := nums[i].AsInt(); ok {
+				if err := fn(key, nums[i+1]); err != nil {
+					return err
+				}
+			}
+		}
+
+		kids, _ := node.Key("/Kids").AsArray()
+		for _, kid := range kids {
+			if err := walk(kid); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return walk(root)
+}
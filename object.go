@@ -0,0 +1,289 @@
+package gofpdi
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Maximum number of references followed by Resolve (an indirect object may consist of a reference only)
+const maxReferenceChain = 32
+
+// Read-only access to the objects of a document.
+//
+// Values returned by these methods are shared with the reader (and its object cache) and must not be modified.
+// Indirect objects are returned as a PDF_TYPE_OBJECT (or PDF_TYPE_STREAM) value whose Value holds the object
+// and whose Stream holds the raw stream data; the PdfValue accessors look through this wrapper.
+
+// Trailer returns the trailer dictionary of the most recent xref section
+func (this *PdfReader) Trailer() *PdfValue {
+	return this.trailer
+}
+
+// Catalog returns the document catalog (the /Root object)
+func (this *PdfReader) Catalog() *PdfValue {
+	return this.catalog
+}
+
+// NumPages returns the number of pages of the document
+func (this *PdfReader) NumPages() int {
+	return this.numPages()
+}
+
+// Resolve follows an indirect reference and returns the object it refers to.  Any other value is returned as is.
+func (this *PdfReader) Resolve(value *PdfValue) (*PdfValue, error) {
+	if value == nil {
+		return nil, errors.New("Value is nil")
+	}
+
+	for i := 0; value.Type == PDF_TYPE_OBJREF || (value.Type == PDF_TYPE_OBJECT && value.Value != nil && value.Value.Type == PDF_TYPE_OBJREF); i++ {
+		if i == maxReferenceChain {
+			return nil, errors.New(fmt.Sprintf("More than %d chained references", maxReferenceChain))
+		}
+
+		if value.Type == PDF_TYPE_OBJECT {
+			value = value.Value
+		}
+
+		resolved, err := this.resolveObject(value)
+		if err != nil {
+			return nil, err
+		}
+		value = resolved
+	}
+
+	return value, nil
+}
+
+// Object returns the indirect object with the given object number and generation
+func (this *PdfReader) Object(id, gen int) (*PdfValue, error) {
+	if !this.hasObject(id, gen) {
+		return nil, &ObjectError{Id: id, Gen: gen, Offset: -1, Err: ErrObjectNotFound}
+	}
+
+	return this.Resolve(&PdfValue{Type: PDF_TYPE_OBJREF, Id: id, Gen: gen})
+}
+
+// ObjectRefs returns references to all objects listed in the cross-reference table, ordered by object number
+func (this *PdfReader) ObjectRefs() []*PdfValue {
+	refs := make([]*PdfValue, 0, len(this.xref)+len(this.xrefStream))
+
+	for id, gens := range this.xref {
+		for gen := range gens {
+			refs = append(refs, &PdfValue{Type: PDF_TYPE_OBJREF, Id: id, Gen: gen})
+		}
+	}
+	for id := range this.xrefStream {
+		if _, ok := this.xref[id]; !ok {
+			refs = append(refs, &PdfValue{Type: PDF_TYPE_OBJREF, Id: id, Gen: 0})
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Id != refs[j].Id {
+			return refs[i].Id < refs[j].Id
+		}
+		return refs[i].Gen < refs[j].Gen
+	})
+
+	return refs
+}
+
+// ForEachObject calls fn for every object listed in the cross-reference table, ordered by object number.
+// It stops at the first object that cannot be read or for which fn returns an error, and returns that error.
+func (this *PdfReader) ForEachObject(fn func(obj *PdfValue) error) error {
+	for _, ref := range this.ObjectRefs() {
+		obj, err := this.resolveObject(ref)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(obj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Page returns the page object of a page number.  Its dictionary is a copy of the page dictionary that also
+// contains the attributes inherited from the page tree (/Resources, /MediaBox, /CropBox and /Rotate).
+func (this *PdfReader) Page(pageno int) (*PdfValue, error) {
+	page, err := this.getPageNode(pageno)
+	if err != nil {
+		return nil, err
+	}
+
+	dict := make(map[string]*PdfValue, len(page.page.Value.Dictionary)+len(page.inherited))
+	for k, v := range page.inherited {
+		dict[k] = v
+	}
+	for k, v := range page.page.Value.Dictionary {
+		dict[k] = v
+	}
+
+	return &PdfValue{
+		Type:  PDF_TYPE_OBJECT,
+		Id:    page.page.Id,
+		Gen:   page.page.Gen,
+		Value: &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: dict},
+	}, nil
+}
+
+// DecodeStream returns the data of a stream object (or a reference to one) with all of its filters applied
+func (this *PdfReader) DecodeStream(value *PdfValue) ([]byte, error) {
+	obj, err := this.Resolve(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return this.decodeStream(obj)
+}
+
+// Check whether an object is listed in the cross-reference table
+func (this *PdfReader) hasObject(id, gen int) bool {
+	if _, ok := this.xref[id][gen]; ok {
+		return true
+	}
+	if _, ok := this.xrefStream[id]; ok && gen == 0 {
+		return true
+	}
+	return false
+}
+
+// Get the value held by an indirect object, or the value itself for direct values
+func (this *PdfValue) direct() *PdfValue {
+	if this != nil && (this.Type == PDF_TYPE_OBJECT || this.Type == PDF_TYPE_STREAM) && this.Value != nil {
+		return this.Value
+	}
+	return this
+}
+
+// IsNull reports whether the value is missing or null
+func (this *PdfValue) IsNull() bool {
+	v := this.direct()
+	return v == nil || v.Type == PDF_TYPE_NULL
+}
+
+// IsRef reports whether the value is an indirect reference, which must be resolved with PdfReader.Resolve
+func (this *PdfValue) IsRef() bool {
+	return this != nil && this.Type == PDF_TYPE_OBJREF
+}
+
+// IsStream reports whether the value is a stream object
+func (this *PdfValue) IsStream() bool {
+	return this != nil && this.Stream != nil
+}
+
+// AsName returns the value of a name (including the leading slash, e.g. "/Page")
+func (this *PdfValue) AsName() (string, bool) {
+	v := this.direct()
+	if v == nil || v.Type != PDF_TYPE_TOKEN || len(v.Token) == 0 || v.Token[0] != '/' {
+		return "", false
+	}
+	return v.Token, true
+}
+
+// AsInt returns the value of an integer
+func (this *PdfValue) AsInt() (int, bool) {
+	v := this.direct()
+	if v == nil || v.Type != PDF_TYPE_NUMERIC {
+		return 0, false
+	}
+	return v.Int, true
+}
+
+// AsFloat returns the value of an integer or real number
+func (this *PdfValue) AsFloat() (float64, bool) {
+	v := this.direct()
+	if v == nil || (v.Type != PDF_TYPE_NUMERIC && v.Type != PDF_TYPE_REAL) {
+		return 0, false
+	}
+	return v.Real, true
+}
+
+// AsBool returns the value of a boolean
+func (this *PdfValue) AsBool() (bool, bool) {
+	v := this.direct()
+	if v == nil || v.Type != PDF_TYPE_BOOLEAN {
+		return false, false
+	}
+	return v.Bool, true
+}
+
+// AsBytes returns the bytes of a literal or hex string, with escape sequences decoded
+func (this *PdfValue) AsBytes() ([]byte, bool) {
+	v := this.direct()
+	if v == nil || (v.Type != PDF_TYPE_STRING && v.Type != PDF_TYPE_HEX) {
+		return nil, false
+	}
+	return pdfStringBytes(v), true
+}
+
+// AsText returns the value of a text string (e.g. a document title), decoded from PDFDocEncoding or UTF-16BE
+func (this *PdfValue) AsText() (string, bool) {
+	data, ok := this.AsBytes()
+	if !ok {
+		return "", false
+	}
+	return decodeTextString(data), true
+}
+
+// AsArray returns the elements of an array
+func (this *PdfValue) AsArray() ([]*PdfValue, bool) {
+	v := this.direct()
+	if v == nil || v.Type != PDF_TYPE_ARRAY {
+		return nil, false
+	}
+	return v.Array, true
+}
+
+// AsDict returns the entries of a dictionary (or of the dictionary of a stream)
+func (this *PdfValue) AsDict() (map[string]*PdfValue, bool) {
+	v := this.direct()
+	if v == nil || v.Type != PDF_TYPE_DICTIONARY {
+		return nil, false
+	}
+	return v.Dictionary, true
+}
+
+// Key returns a dictionary entry (e.g. "/Type"), or nil if the value is not a dictionary or has no such entry
+func (this *PdfValue) Key(key string) *PdfValue {
+	dict, _ := this.AsDict()
+	return dict[key]
+}
+
+// Keys returns the sorted keys of a dictionary
+func (this *PdfValue) Keys() []string {
+	dict, _ := this.AsDict()
+
+	keys := make([]string, 0, len(dict))
+	for k := range dict {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Len returns the number of elements of an array or entries of a dictionary
+func (this *PdfValue) Len() int {
+	v := this.direct()
+	if v == nil {
+		return 0
+	}
+	if v.Type == PDF_TYPE_ARRAY {
+		return len(v.Array)
+	}
+	return len(v.Dictionary)
+}
+
+// Index returns an element of an array, or nil if the value is not an array or the index is out of range
+func (this *PdfValue) Index(i int) *PdfValue {
+	array, _ := this.AsArray()
+	if i < 0 || i >= len(array) {
+		return nil
+	}
+	return array[i]
+}
@@ -0,0 +1,106 @@
+package gofpdi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestObjectAccessors(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R /Values 3 0 R >>",
+		"<< /Type /Pages /Kids [6 0 R] /Count 1 /MediaBox [0 0 612 792] >>",
+		"<< /Name /Foo /Int 42 /Real 1.5 /Bool true /Str (a\\(b\\)) /Hex <4869> /Arr [1 2 4 0 R] /Ref 4 0 R /Null null >>",
+		"5 0 R",
+		"(Target)",
+		"<< /Type /Page /Parent 2 0 R >>",
+	)
+
+	if name, _ := reader.Catalog().Key("/Type").AsName(); name != "/Catalog" {
+		t.Errorf("catalog /Type %q", name)
+	}
+
+	values, err := reader.Resolve(reader.Catalog().Key("/Values"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name, ok := values.Key("/Name").AsName(); !ok || name != "/Foo" {
+		t.Errorf("AsName = %q, %v", name, ok)
+	}
+	if i, ok := values.Key("/Int").AsInt(); !ok || i != 42 {
+		t.Errorf("AsInt = %d, %v", i, ok)
+	}
+	if _, ok := values.Key("/Real").AsInt(); ok {
+		t.Error("AsInt accepted a real number")
+	}
+	if f, ok := values.Key("/Real").AsFloat(); !ok || f != 1.5 {
+		t.Errorf("AsFloat = %v, %v", f, ok)
+	}
+	if b, ok := values.Key("/Bool").AsBool(); !ok || !b {
+		t.Errorf("AsBool = %v, %v", b, ok)
+	}
+	if s, ok := values.Key("/Str").AsBytes(); !ok || string(s) != "a(b)" {
+		t.Errorf("AsBytes = %q, %v", s, ok)
+	}
+	if s, ok := values.Key("/Hex").AsText(); !ok || s != "Hi" {
+		t.Errorf("AsText = %q, %v", s, ok)
+	}
+	if !values.Key("/Null").IsNull() || !values.Key("/Missing").IsNull() {
+		t.Error("IsNull is false for a null or missing value")
+	}
+
+	array := values.Key("/Arr")
+	if array.Len() != 3 || !array.Index(2).IsRef() || array.Index(3) != nil || values.Key("/Int").Index(0) != nil {
+		t.Errorf("array %v", array)
+	}
+
+	want := []string{"/Arr", "/Bool", "/Hex", "/Int", "/Name", "/Null", "/Real", "/Ref", "/Str"}
+	if keys := values.Keys(); !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys = %v, want %v", keys, want)
+	}
+
+	// Resolve follows the reference held by object 4
+	target, err := reader.Resolve(values.Key("/Ref"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := target.AsText(); s != "Target" {
+		t.Errorf("resolved reference %q, want %q", s, "Target")
+	}
+
+	// The page dictionary includes the inherited /MediaBox
+	page, err := reader.Page(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Id != 6 || page.Key("/MediaBox").Len() != 4 {
+		t.Errorf("page %d with /MediaBox %v", page.Id, page.Key("/MediaBox"))
+	}
+}
+
+func TestForEachObject(t *testing.T) {
+	reader := readTestFile(t, "xref-stream.pdf")
+
+	ids := make([]int, 0)
+	err := reader.ForEachObject(func(obj *PdfValue) error {
+		ids = append(ids, obj.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != len(reader.ObjectRefs()) || ids[0] != 1 {
+		t.Errorf("visited objects %v", ids)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = reader.ForEachObject(func(obj *PdfValue) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("ForEachObject = %v after %d objects, want to stop after the first one", err, n)
+	}
+}
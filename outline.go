@@ -0,0 +1,190 @@
+package gofpdi
+
+import (
+	"github.com/pkg/errors"
+)
+
+// OutlineItem is an entry of the document outline (bookmarks)
+type OutlineItem struct {
+	Title    string
+	Page     int        // target page number, 0 if the item does not point to a page of this document
+	Fit      string     // how the target page is displayed (/XYZ, /Fit, /FitH, ...), empty if unknown
+	Color    [3]float64 // RGB color of the title, each component in the range 0.0 to 1.0
+	Bold     bool
+	Italic   bool
+	Open     bool   // whether the children are shown initially
+	Action   string // action of the item, if it has one instead of a destination (e.g. /GoTo, /URI, /GoToR)
+	URI      string // target of a /URI action
+	Children []*OutlineItem
+}
+
+// Outlines returns the top level items of the document outline, or an empty slice if there is none.
+// Destinations are resolved to page numbers, including named destinations and /GoTo actions.
+func (this *PdfReader) Outlines() ([]*OutlineItem, error) {
+	outlinesSpec := this.catalog.Key("/Outlines")
+	if outlinesSpec == nil {
+		return make([]*OutlineItem, 0), nil
+	}
+
+	outlines, err := this.Resolve(outlinesSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve outlines")
+	}
+
+	// Guard against /First and /Next entries that refer back to an earlier item
+	visited := make(map[int]bool, 0)
+
+	return this.readOutlineItems(outlines.Key("/First"), visited)
+}
+
+// Outlines is the same as PdfReader.Outlines
+func (e *Exporter) Outlines() ([]*OutlineItem, error) {
+	return e.reader.Outlines()
+}
+
+// Read an outline item and its siblings (following /Next), along with their children
+func (this *PdfReader) readOutlineItems(first *PdfValue, visited map[int]bool) ([]*OutlineItem, error) {
+	items := make([]*OutlineItem, 0)
+
+	itemSpec := first
+	for itemSpec.IsRef() && !visited[itemSpec.Id] {
+		visited[itemSpec.Id] = true
+
+		item, err := this.Resolve(itemSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve outline item")
+		}
+
+		outline := &OutlineItem{}
+
+		if title, err := this.Resolve(item.Key("/Title")); err == nil {
+			outline.Title, _ = title.AsText()
+		}
+
+		if color, ok := item.Key("/C").AsArray(); ok && len(color) == 3 {
+			for i := 0; i < 3; i++ {
+				outline.Color[i], _ = color[i].AsFloat()
+			}
+		}
+
+		if flags, ok := item.Key("/F").AsInt(); ok {
+			outline.Italic = flags&1 != 0
+			outline.Bold = flags&2 != 0
+		}
+
+		if count, ok := item.Key("/Count").AsInt(); ok {
+			outline.Open = count > 0
+		}
+
+		if dest := item.Key("/Dest"); dest != nil {
+			outline.Page, outline.Fit, err = this.resolveDestination(dest)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to resolve outline destination")
+			}
+		} else if actionSpec := item.Key("/A"); actionSpec != nil {
+			action, err := this.Resolve(actionSpec)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to resolve outline action")
+			}
+
+			outline.Action, _ = action.Key("/S").AsName()
+			switch outline.Action {
+			case "/GoTo":
+				outline.Page, outline.Fit, err = this.resolveDestination(action.Key("/D"))
+				if err != nil {
+					return nil, errors.Wrap(err, "Failed to resolve outline destination")
+				}
+			case "/URI":
+				if uri, err := this.Resolve(action.Key("/URI")); err == nil {
+					outline.URI, _ = uri.AsText()
+				}
+			}
+		}
+
+		if item.Key("/First") != nil {
+			outline.Children, err = this.readOutlineItems(item.Key("/First"), visited)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		items = append(items, outline)
+		itemSpec = item.Key("/Next")
+	}
+
+	return items, nil
+}
+
+// Resolve a destination to a page number and the way the page is displayed.  The destination may be an
+// explicit destination array, a name looked up in the /Dests dictionary of the catalog, or a string looked
+// up in the /Dests name tree.  The page number is 0 if the destination cannot be found.
+func (this *PdfReader) resolveDestination(dest *PdfValue) (int, string, error) {
+	var err error
+
+	for i := 0; dest != nil && i < maxReferenceChain; i++ {
+		dest, err = this.Resolve(dest)
+		if err != nil {
+			return 0, "", err
+		}
+
+		if name, ok := dest.AsName(); ok {
+			if this.catalog.Key("/Dests") == nil {
+				return 0, "", nil
+			}
+			dests, err := this.Resolve(this.catalog.Key("/Dests"))
+			if err != nil {
+				return 0, "", err
+			}
+			dest = dests.Key(name)
+			continue
+		}
+
+		if name, ok := dest.AsBytes(); ok {
+			if this.catalog.Key("/Names") == nil {
+				return 0, "", nil
+			}
+			names, err := this.Resolve(this.catalog.Key("/Names"))
+			if err != nil {
+				return 0, "", err
+			}
+			if names.Key("/Dests") == nil {
+				return 0, "", nil
+			}
+			dest, err = this.lookupNameTree(names.Key("/Dests"), name)
+			if err != nil {
+				return 0, "", err
+			}
+			continue
+		}
+
+		// Named destinations may be dictionaries holding the destination in /D
+		if d := dest.Key("/D"); d != nil {
+			dest = d
+			continue
+		}
+
+		array, ok := dest.AsArray()
+		if !ok || len(array) == 0 {
+			return 0, "", nil
+		}
+
+		fit := ""
+		if len(array) > 1 {
+			fit, _ = array[1].AsName()
+		}
+
+		if array[0].IsRef() {
+			pageno, err := this.pageNumber(array[0].Id)
+			return pageno, fit, err
+		}
+
+		// Destinations of remote documents use page indexes, but some writers use them for local ones too
+		if index, ok := array[0].AsInt(); ok && index >= 0 && index < this.numPages() {
+			return index + 1, fit, nil
+		}
+
+		return 0, fit, nil
+	}
+
+	return 0, "", nil
+}
@@ -0,0 +1,53 @@
+package gofpdi
+
+import "testing"
+
+func TestOutlines(t *testing.T) {
+	reader := readTestFile(t, "outline.pdf")
+
+	items, err := reader.Outlines()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Explicit destinations, named destinations in the name tree and in /Dests, /GoTo and /URI actions, a page
+	// index instead of a page reference, a missing destination, and a /Next that loops back to the first item
+	tests := []struct {
+		title  string
+		page   int
+		fit    string
+		action string
+	}{
+		{"Intro", 1, "/XYZ", ""},
+		{"Chapter 5", 5, "/Fit", ""},
+		{"Web", 0, "", "/URI"},
+		{"C3", 4, "/FitH", "/GoTo"},
+		{"D4", 4, "/Fit", ""},
+		{"Nowhere", 0, "", ""},
+	}
+
+	if len(items) != len(tests) {
+		t.Fatalf("%d items, want %d", len(items), len(tests))
+	}
+	for i, test := range tests {
+		item := items[i]
+		if item.Title != test.title || item.Page != test.page || item.Fit != test.fit || item.Action != test.action {
+			t.Errorf("item %d = %q page %d %s %s, want %q page %d %s %s", i, item.Title, item.Page, item.Fit, item.Action,
+				test.title, test.page, test.fit, test.action)
+		}
+	}
+
+	intro := items[0]
+	if !intro.Open || !intro.Bold || !intro.Italic || intro.Color != [3]float64{1, 0, 0} {
+		t.Errorf("intro %+v", intro)
+	}
+	if len(intro.Children) != 2 || intro.Children[0].Title != "É" || intro.Children[0].Page != 2 || intro.Children[1].Page != 3 {
+		t.Errorf("intro children %+v", intro.Children)
+	}
+	if items[1].Open {
+		t.Error("chapter 5 is open")
+	}
+	if items[2].URI != "https://example.com" {
+		t.Errorf("URI %q", items[2].URI)
+	}
+}
@@ -0,0 +1,179 @@
+package gofpdi
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// PageBox is a rectangle of a page (PDF 32000-1:2008, 14.11.2) in the user space units of the page.  Multiply by
+// the UserUnit of the page to get its size in points (1/72 inch).
+type PageBox struct {
+	Llx float64
+	Lly float64
+	Urx float64
+	Ury float64
+}
+
+// Width returns the width of the box
+func (this PageBox) Width() float64 {
+	return this.Urx - this.Llx
+}
+
+// Height returns the height of the box
+func (this PageBox) Height() float64 {
+	return this.Ury - this.Lly
+}
+
+// Intersection of two boxes.  Returns false if they do not overlap.
+func (this PageBox) intersect(other PageBox) (PageBox, bool) {
+	result := PageBox{
+		Llx: math.Max(this.Llx, other.Llx),
+		Lly: math.Max(this.Lly, other.Lly),
+		Urx: math.Min(this.Urx, other.Urx),
+		Ury: math.Min(this.Ury, other.Ury),
+	}
+	return result, result.Width() > 0 && result.Height() > 0
+}
+
+// PageBoxes holds the effective boxes of a page.  Boxes that are not defined by the page (or inherited from the
+// page tree) take their default values: the crop box defaults to the media box, and the bleed, trim and art boxes
+// default to the crop box.  The crop box is clipped to the media box, and the other boxes to the crop box.
+type PageBoxes struct {
+	MediaBox PageBox
+	CropBox  PageBox
+	BleedBox PageBox
+	TrimBox  PageBox
+	ArtBox   PageBox
+	UserUnit float64 // size of a user space unit in points, 1 unless the page sets /UserUnit
+	Rotate   int     // clockwise rotation of the page in degrees: 0, 90, 180 or 270
+}
+
+// Box returns a box by its name (e.g. "/TrimBox")
+func (this *PageBoxes) Box(name string) (PageBox, bool) {
+	switch name {
+	case "/MediaBox":
+		return this.MediaBox, true
+	case "/CropBox":
+		return this.CropBox, true
+	case "/BleedBox":
+		return this.BleedBox, true
+	case "/TrimBox":
+		return this.TrimBox, true
+	case "/ArtBox":
+		return this.ArtBox, true
+	}
+	return PageBox{}, false
+}
+
+// Media box used for pages that do not have one (US Letter)
+var defaultMediaBox = PageBox{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+
+// PageBoxes returns the effective boxes, user unit and rotation of a page
+func (this *PdfReader) PageBoxes(pageno int) (*PageBoxes, error) {
+	if err := this.checkPage(pageno); err != nil {
+		return nil, err
+	}
+
+	page, err := this.getPageNode(pageno)
+	if err != nil {
+		return nil, pageError(pageno, errors.Wrap(err, "Failed to resolve page object"))
+	}
+
+	boxes := &PageBoxes{UserUnit: 1}
+
+	mediaBox, ok, err := this.readPageBox(page, "/MediaBox")
+	if err != nil {
+		return nil, pageError(pageno, err)
+	}
+	if !ok {
+		mediaBox = defaultMediaBox
+	}
+	boxes.MediaBox = mediaBox
+
+	boxes.CropBox = mediaBox
+	cropBox, ok, err := this.readPageBox(page, "/CropBox")
+	if err != nil {
+		return nil, pageError(pageno, err)
+	}
+	if ok {
+		if cropBox, ok = cropBox.intersect(mediaBox); ok {
+			boxes.CropBox = cropBox
+		}
+	}
+
+	for _, name := range []string{"/BleedBox", "/TrimBox", "/ArtBox"} {
+		box, ok, err := this.readPageBox(page, name)
+		if err != nil {
+			return nil, pageError(pageno, err)
+		}
+		if ok {
+			box, ok = box.intersect(boxes.CropBox)
+		}
+		if !ok {
+			box = boxes.CropBox
+		}
+
+		switch name {
+		case "/BleedBox":
+			boxes.BleedBox = box
+		case "/TrimBox":
+			boxes.TrimBox = box
+		case "/ArtBox":
+			boxes.ArtBox = box
+		}
+	}
+
+	if userUnit, ok := page.page.Key("/UserUnit").AsFloat(); ok && userUnit > 0 {
+		boxes.UserUnit = userUnit
+	}
+
+	rotation, err := this.getPageRotation(pageno)
+	if err != nil {
+		return nil, pageError(pageno, err)
+	}
+	if rotate, ok := rotation.AsInt(); ok {
+		boxes.Rotate = (rotate%360 + 360) % 360
+	}
+
+	return boxes, nil
+}
+
+// PageBoxes is the same as PdfReader.PageBoxes
+func (e *Exporter) PageBoxes(pageno int) (*PageBoxes, error) {
+	return e.reader.PageBoxes(pageno)
+}
+
+// Read a page box, normalized so that the lower left corner comes first.  Only /MediaBox and /CropBox are
+// inherited from the page tree.  Returns false if the page does not define the box.
+func (this *PdfReader) readPageBox(page *pageNode, name string) (PageBox, bool, error) {
+	box, ok := page.page.Value.Dictionary[name]
+	if !ok && (name == "/MediaBox" || name == "/CropBox") {
+		box, ok = page.attribute(name)
+	}
+	if !ok {
+		return PageBox{}, false, nil
+	}
+
+	box, err := this.resolveDirect(box)
+	if err != nil {
+		return PageBox{}, false, errors.Wrap(err, "Failed to resolve "+name)
+	}
+
+	coords, ok := numberArray(box, 4)
+	if !ok {
+		return PageBox{}, false, errors.New("Invalid " + name)
+	}
+
+	result := PageBox{
+		Llx: math.Min(coords[0], coords[2]),
+		Lly: math.Min(coords[1], coords[3]),
+		Urx: math.Max(coords[0], coords[2]),
+		Ury: math.Max(coords[1], coords[3]),
+	}
+	if result.Width() == 0 || result.Height() == 0 {
+		return PageBox{}, false, nil
+	}
+
+	return result, true, nil
+}
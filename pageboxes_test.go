@@ -0,0 +1,74 @@
+package gofpdi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPageBoxes(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R 6 0 R 7 0 R] /Count 5 /MediaBox [0 0 842 595] /Rotate 90 >>",
+		// Inherited media box and rotation; the crop box is clipped to the media box, the trim box to the crop box
+		"<< /Type /Page /Parent 2 0 R /CropBox [10 10 900 585] /TrimBox [0 20 400 300] >>",
+		// Corners in any order, a bleed box and an art box outside the crop box
+		"<< /Type /Page /Parent 2 0 R /MediaBox [612 792 0 0] /BleedBox [0 0 50 50] /ArtBox [1000 1000 2000 2000] /Rotate -90 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] /UserUnit 10 /Rotate 0 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 0 100] /Rotate 540 >>",
+		"<< /Type /Page /Parent 2 0 R /CropBox [0 0 10 x] >>",
+	)
+
+	a4 := PageBox{0, 0, 842, 595}
+	crop := PageBox{10, 10, 842, 585}
+	letter := PageBox{0, 0, 612, 792}
+	tests := []PageBoxes{
+		{MediaBox: a4, CropBox: crop, BleedBox: crop, TrimBox: PageBox{10, 20, 400, 300}, ArtBox: crop, UserUnit: 1, Rotate: 90},
+		{MediaBox: letter, CropBox: letter, BleedBox: PageBox{0, 0, 50, 50}, TrimBox: letter, ArtBox: letter, UserUnit: 1, Rotate: 270},
+		{MediaBox: PageBox{0, 0, 100, 100}, CropBox: PageBox{0, 0, 100, 100}, BleedBox: PageBox{0, 0, 100, 100},
+			TrimBox: PageBox{0, 0, 100, 100}, ArtBox: PageBox{0, 0, 100, 100}, UserUnit: 10, Rotate: 0},
+		// An empty media box is not inherited either, and falls back to US Letter
+		{MediaBox: letter, CropBox: letter, BleedBox: letter, TrimBox: letter, ArtBox: letter, UserUnit: 1, Rotate: 180},
+	}
+
+	for i, want := range tests {
+		boxes, err := reader.PageBoxes(i + 1)
+		if err != nil {
+			t.Fatalf("page %d: %v", i+1, err)
+		}
+		if *boxes != want {
+			t.Errorf("page %d: boxes %+v, want %+v", i+1, *boxes, want)
+		}
+	}
+
+	if _, err := reader.PageBoxes(5); err == nil {
+		t.Error("page 5: invalid crop box accepted")
+	}
+	if _, err := reader.PageBoxes(6); !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("page 6: %v, want ErrPageOutOfRange", err)
+	}
+}
+
+func TestPageBoxesUserUnit(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [10 0 110 50] /UserUnit 2 >>",
+	)
+
+	// Template sizes are in points, the corners in user space units
+	writer, err := NewPdfWriter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.ImportPage(reader, 1, "/MediaBox"); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := writer.tpls[0]
+	if tpl.Box["w"] != 200 || tpl.Box["h"] != 100 || tpl.Box["llx"] != 10 || tpl.Box["urx"] != 110 {
+		t.Errorf("template box %v", tpl.Box)
+	}
+	if matrix := writer.templateMatrix(tpl); matrix != [6]float64{2, 0, 0, 2, -20, 0} {
+		t.Errorf("template matrix %v", matrix)
+	}
+}
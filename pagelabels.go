@@ -0,0 +1,175 @@
+package gofpdi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Largest number formatted as a roman numeral or letters; larger numbers (which only come with a bogus /St) are
+// formatted as decimal numbers, as their labels would grow with the number
+const maxLabelNumeral = 4999
+
+// A range of pages labelled with the same style and prefix (an entry of the /PageLabels number tree)
+type pageLabelRange struct {
+	start  int    // index of the first page of the range
+	style  string // /D, /R, /r, /A or /a, empty for labels consisting of the prefix only
+	prefix string
+	first  int // numeric value of the first page of the range
+}
+
+// PageLabels returns the labels of all pages (PDF 32000-1:2008, 12.4.2), indexed by page number - 1.  Pages are
+// labelled with their page number if the document does not define page labels for them.
+func (this *PdfReader) PageLabels() ([]string, error) {
+	ranges, err := this.pageLabelRanges()
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make([]string, this.numPages())
+	for i := range labels {
+		labels[i] = pageLabel(ranges, i)
+	}
+
+	return labels, nil
+}
+
+// PageLabel returns the label of a page, e.g. "iv" or "A-3"
+func (this *PdfReader) PageLabel(pageno int) (string, error) {
+	if err := this.checkPage(pageno); err != nil {
+		return "", err
+	}
+
+	ranges, err := this.pageLabelRanges()
+	if err != nil {
+		return "", err
+	}
+
+	return pageLabel(ranges, pageno-1), nil
+}
+
+// PageByLabel returns the number of the first page with the given label
+func (this *PdfReader) PageByLabel(label string) (int, error) {
+	labels, err := this.PageLabels()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, l := range labels {
+		if l == label {
+			return i + 1, nil
+		}
+	}
+
+	return 0, errors.Wrap(ErrPageLabelNotFound, label)
+}
+
+// PageLabel is the same as PdfReader.PageLabel
+func (e *Exporter) PageLabel(pageno int) (string, error) {
+	return e.reader.PageLabel(pageno)
+}
+
+// PageByLabel is the same as PdfReader.PageByLabel
+func (e *Exporter) PageByLabel(label string) (int, error) {
+	return e.reader.PageByLabel(label)
+}
+
+// Read the page label ranges from the /PageLabels number tree of the catalog, ordered by their first page
+func (this *PdfReader) pageLabelRanges() ([]pageLabelRange, error) {
+	ranges := make([]pageLabelRange, 0)
+
+	root := this.catalog.Key("/PageLabels")
+	if root == nil {
+		return ranges, nil
+	}
+
+	err := this.walkNumberTree(root, func(key int, value *PdfValue) error {
+		dict, err := this.Resolve(value)
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve page label")
+		}
+
+		r := pageLabelRange{start: key, first: 1}
+		r.style, _ = dict.Key("/S").AsName()
+		if prefix, err := this.Resolve(dict.Key("/P")); err == nil {
+			r.prefix, _ = prefix.AsText()
+		}
+		if st, ok := dict.Key("/St").AsInt(); ok && st > 0 {
+			r.first = st
+		}
+
+		ranges = append(ranges, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+
+	return ranges, nil
+}
+
+// Get the label of the page with the given index from the page label ranges
+func pageLabel(ranges []pageLabelRange, index int) string {
+	i := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i].start > index
+	}) - 1
+	if i < 0 {
+		return strconv.Itoa(index + 1)
+	}
+
+	r := ranges[i]
+	n := r.first + index - r.start
+
+	switch r.style {
+	case "/D":
+		return r.prefix + strconv.Itoa(n)
+	case "/R":
+		return r.prefix + romanNumeral(n)
+	case "/r":
+		return r.prefix + strings.ToLower(romanNumeral(n))
+	case "/A":
+		return r.prefix + alphaNumeral(n)
+	case "/a":
+		return r.prefix + strings.ToLower(alphaNumeral(n))
+	}
+
+	return r.prefix
+}
+
+// Format a number as an uppercase roman numeral.  Thousands are repeated M's.
+func romanNumeral(n int) string {
+	if n > maxLabelNumeral {
+		return strconv.Itoa(n)
+	}
+
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+
+	var result strings.Builder
+	for i, value := range values {
+		for n >= value {
+			result.WriteString(symbols[i])
+			n -= value
+		}
+	}
+
+	return result.String()
+}
+
+// Format a number as uppercase letters: A to Z, then AA to ZZ, AAA to ZZZ and so on
+func alphaNumeral(n int) string {
+	if n < 1 {
+		return ""
+	}
+	if n > maxLabelNumeral {
+		return strconv.Itoa(n)
+	}
+
+	return strings.Repeat(string(rune('A'+(n-1)%26)), (n-1)/26+1)
+}
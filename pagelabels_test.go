@@ -0,0 +1,89 @@
+package gofpdi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPageLabels(t *testing.T) {
+	reader := readTestFile(t, "labels.pdf")
+
+	labels, err := reader.PageLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"i", "ii", "iii", "iv", "1", "2", "3", "4", "A-3", "A-4", "AA", "Cover"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels %q, want %q", labels, want)
+	}
+
+	label, err := reader.PageLabel(9)
+	if err != nil || label != "A-3" {
+		t.Errorf("page 9 label %q, %v, want A-3", label, err)
+	}
+	if _, err := reader.PageLabel(13); !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("page 13: %v, want ErrPageOutOfRange", err)
+	}
+
+	pageno, err := reader.PageByLabel("iv")
+	if err != nil || pageno != 4 {
+		t.Errorf("page of label iv %d, %v, want 4", pageno, err)
+	}
+	if _, err := reader.PageByLabel("v"); !errors.Is(err, ErrPageLabelNotFound) {
+		t.Errorf("label v: %v, want ErrPageLabelNotFound", err)
+	}
+}
+
+func TestPageLabelsWithoutLabels(t *testing.T) {
+	reader := readTestFile(t, "simple.pdf")
+
+	labels, err := reader.PageLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels %q, want %q", labels, want)
+	}
+}
+
+func TestPageLabelNumerals(t *testing.T) {
+	// A huge /St must not make labels grow with the number
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R /PageLabels << /Nums [0 << /S /R /St 2000000000 >> 1 << /S /a /St 2000000000 >>] >> >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+	)
+
+	labels, err := reader.PageLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2000000000", "2000000000"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels %q, want %q", labels, want)
+	}
+
+	tests := []struct {
+		n            int
+		roman, alpha string
+	}{
+		{1, "I", "A"},
+		{4, "IV", "D"},
+		{26, "XXVI", "Z"},
+		{27, "XXVII", "AA"},
+		{1994, "MCMXCIV", ""},
+		{4999, "MMMMCMXCIX", ""},
+		{5000, "5000", "5000"},
+	}
+
+	for _, test := range tests {
+		if got := romanNumeral(test.n); got != test.roman {
+			t.Errorf("romanNumeral(%d) = %q, want %q", test.n, got, test.roman)
+		}
+		if got := alphaNumeral(test.n); test.alpha != "" && got != test.alpha {
+			t.Errorf("alphaNumeral(%d) = %q, want %q", test.n, got, test.alpha)
+		}
+	}
+}
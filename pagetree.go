@@ -0,0 +1,352 @@
+package gofpdi
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Page attributes that a page inherits from its ancestors in the page tree if it does not define them itself
+var inheritablePageAttributes = []string{"/Resources", "/MediaBox", "/CropBox", "/Rotate"}
+
+// Returned by findPage when the /Count entries of the page tree cannot be trusted
+var errPageTreeMismatch = errors.New("Page tree /Count does not match its /Kids")
+
+// A page object along with the inheritable attributes defined by its ancestors in the page tree
+type pageNode struct {
+	page      *PdfValue
+	inherited map[string]*PdfValue
+}
+
+// Get an attribute of the page, or the value inherited from the nearest ancestor that defines it
+func (this *pageNode) attribute(key string) (*PdfValue, bool) {
+	if v, ok := this.page.Value.Dictionary[key]; ok {
+		return v, true
+	}
+
+	v, ok := this.inherited[key]
+	return v, ok
+}
+
+// Add the inheritable attributes defined by a page tree node to the ones inherited from its ancestors
+func inheritPageAttributes(inherited map[string]*PdfValue, node *PdfValue) map[string]*PdfValue {
+	result := make(map[string]*PdfValue, len(inheritablePageAttributes))
+
+	for k, v := range inherited {
+		result[k] = v
+	}
+	for _, key := range inheritablePageAttributes {
+		if v, ok := node.Value.Dictionary[key]; ok {
+			result[key] = v
+		}
+	}
+
+	return result
+}
+
+// Get the type of a page tree node, /Pages or /Page.  Nodes without a valid /Type are recognized by their /Kids.
+func pageTreeNodeType(node *PdfValue) string {
+	if t, ok := node.Value.Dictionary["/Type"]; ok && (t.Token == "/Pages" || t.Token == "/Page") {
+		return t.Token
+	}
+
+	if _, ok := node.Value.Dictionary["/Kids"]; ok {
+		return "/Pages"
+	}
+
+	return "/Page"
+}
+
+// Get the number of pages below a page tree node, or -1 if its /Count is missing or invalid
+func (this *PdfReader) pageTreeCount(node *PdfValue) int {
+	if pageTreeNodeType(node) == "/Page" {
+		return 1
+	}
+
+	countSpec, ok := node.Value.Dictionary["/Count"]
+	if !ok {
+		return -1
+	}
+
+	count, err := this.resolveDirect(countSpec)
+	if err != nil || count.Type != PDF_TYPE_NUMERIC || count.Int < 0 {
+		return -1
+	}
+
+	return count.Int
+}
+
+// Resolve the kids of a page tree node.  A missing /Kids is treated as an empty array, and entries that are
+// not references to dictionaries are skipped.
+func (this *PdfReader) pageTreeKids(node *PdfValue) ([]*PdfValue, error) {
+	kidsSpec, ok := node.Value.Dictionary["/Kids"]
+	if !ok {
+		return nil, nil
+	}
+
+	kids, err := this.resolveDirect(kidsSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve kids")
+	}
+
+	result := make([]*PdfValue, 0, len(kids.Array))
+
+	for _, kidSpec := range kids.Array {
+		if kidSpec.Type != PDF_TYPE_OBJREF {
+			log.Debugf("skipping page tree node %d kid that is not an indirect object", node.Id)
+			continue
+		}
+
+		kid, err := this.resolveObject(kidSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve page/pages object")
+		}
+
+		if kid.Value == nil || kid.Value.Type != PDF_TYPE_DICTIONARY {
+			log.Debugf("skipping page tree node %d kid %d that is not a dictionary", node.Id, kid.Id)
+			continue
+		}
+
+		result = append(result, kid)
+	}
+
+	return result, nil
+}
+
+// Resolve the kids of a page tree node and get the number of pages below each of them.  ok is false if the
+// /Count of a kid is missing, or if the counts of the kids do not add up to the /Count of the node.
+func (this *PdfReader) countPageTreeKids(node *PdfValue) (kids []*PdfValue, counts []int, ok bool, err error) {
+	kids, err = this.pageTreeKids(node)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	counts = make([]int, len(kids))
+	total := 0
+
+	for i, kid := range kids {
+		counts[i] = this.pageTreeCount(kid)
+		if counts[i] < 0 {
+			return kids, counts, false, nil
+		}
+		total += counts[i]
+	}
+
+	return kids, counts, total == this.pageTreeCount(node), nil
+}
+
+// Walk the whole page tree and return its pages in order, ignoring /Count.  Nodes that are reached a second
+// time (because of a cycle or a subtree shared by two parents) are skipped.
+func (this *PdfReader) walkPageTree() ([]*pageNode, error) {
+	pages := make([]*pageNode, 0)
+	visited := make(map[int]bool, 0)
+
+	var walk func(node *PdfValue, inherited map[string]*PdfValue) error
+	walk = func(node *PdfValue, inherited map[string]*PdfValue) error {
+		if visited[node.Id] {
+			log.Debugf("skipping page tree node %d that was already visited", node.Id)
+			return nil
+		}
+		visited[node.Id] = true
+
+		if pageTreeNodeType(node) == "/Page" {
+			pages = append(pages, &pageNode{page: node, inherited: inherited})
+			return nil
+		}
+
+		kids, err := this.pageTreeKids(node)
+		if err != nil {
+			return err
+		}
+
+		inherited = inheritPageAttributes(inherited, node)
+		for _, kid := range kids {
+			if err := walk(kid, inherited); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(this.pagesRoot, nil); err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+// Walk the whole page tree and replace the page list and page count with the pages actually found
+func (this *PdfReader) loadAllPages() error {
+	pages, err := this.walkPageTree()
+	if err != nil {
+		return errors.Wrap(err, "Failed to walk page tree")
+	}
+
+	this.pagesMu.Lock()
+	defer this.pagesMu.Unlock()
+
+	if len(pages) != this.pageCount {
+		log.Debugf("page tree /Count is %d, found %d pages", this.pageCount, len(pages))
+	}
+
+	this.pages = pages
+	this.pageCount = len(pages)
+	this.pagesWalked = true
+	this.pageNumbers = nil
+
+	return nil
+}
+
+// Descend the page tree from the root to a page, skipping subtrees that end before it using their /Count,
+// so only the nodes on the path to the page and their kids are resolved.  errPageTreeMismatch is returned if
+// a /Count on the way is missing or wrong, or if a node refers back to one of its ancestors.
+func (this *PdfReader) findPage(pageno int) (*pageNode, error) {
+	node := this.pagesRoot
+	inherited := inheritPageAttributes(nil, node)
+
+	// Page number of the first page below node
+	first := 1
+
+	visited := make(map[int]bool, 0)
+
+	for {
+		visited[node.Id] = true
+
+		kids, counts, ok, err := this.countPageTreeKids(node)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errPageTreeMismatch
+		}
+
+		var next *PdfValue
+
+		for i, kid := range kids {
+			if visited[kid.Id] {
+				return nil, errPageTreeMismatch
+			}
+
+			if pageTreeNodeType(kid) == "/Page" {
+				page := &pageNode{page: kid, inherited: inherited}
+				this.setPage(first, page)
+				if first == pageno {
+					return page, nil
+				}
+				first++
+			} else if pageno < first+counts[i] {
+				next = kid
+				break
+			} else {
+				first += counts[i]
+			}
+		}
+
+		if next == nil {
+			return nil, errPageTreeMismatch
+		}
+
+		node = next
+		inherited = inheritPageAttributes(inherited, node)
+	}
+}
+
+// Get the page object of a page number
+func (this *PdfReader) getPage(pageno int) (*PdfValue, error) {
+	page, err := this.getPageNode(pageno)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.page, nil
+}
+
+// Get the page object of a page number along with its inherited attributes.  If the /Count entries of the
+// page tree turn out to be wrong, the whole tree is walked once and the page count is corrected.
+func (this *PdfReader) getPageNode(pageno int) (*pageNode, error) {
+	page, err := this.loadedPage(pageno)
+	if err != nil || page != nil {
+		return page, err
+	}
+
+	page, err = this.findPage(pageno)
+	if err != errPageTreeMismatch {
+		return page, err
+	}
+
+	log.Debugf("page tree /Count does not match its kids, walking the whole page tree")
+
+	if err := this.loadAllPages(); err != nil {
+		return nil, err
+	}
+
+	page, err = this.loadedPage(pageno)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		return nil, &PageError{Page: pageno, Err: errors.New("Page not found in page tree")}
+	}
+
+	return page, nil
+}
+
+// Get the page object of a page number if it has been found already, or nil.  The page number is checked under the
+// same lock, as another goroutine walking the page tree may replace the page list with a shorter one.
+func (this *PdfReader) loadedPage(pageno int) (*pageNode, error) {
+	this.pagesMu.Lock()
+	defer this.pagesMu.Unlock()
+
+	if pageno < 1 || pageno > len(this.pages) {
+		return nil, &PageError{Page: pageno, Err: ErrPageOutOfRange}
+	}
+
+	return this.pages[pageno-1], nil
+}
+
+// Remember the page object of a page number found while descending the page tree
+func (this *PdfReader) setPage(pageno int, page *pageNode) {
+	this.pagesMu.Lock()
+	defer this.pagesMu.Unlock()
+
+	// Page numbers found by descending are not valid anymore once the whole tree has been walked
+	if this.pagesWalked || pageno < 1 || pageno > len(this.pages) {
+		return
+	}
+
+	this.pages[pageno-1] = page
+}
+
+// Get the number of pages.  It can change once if the page tree /Count turns out to be wrong.
+func (this *PdfReader) numPages() int {
+	this.pagesMu.Lock()
+	defer this.pagesMu.Unlock()
+
+	return this.pageCount
+}
+
+// Get the page number of a page object, or 0 if it is not part of the page tree.  The whole page tree is
+// walked the first time this is called.
+func (this *PdfReader) pageNumber(id int) (int, error) {
+	this.pagesMu.Lock()
+	walked := this.pagesWalked
+	this.pagesMu.Unlock()
+
+	if !walked {
+		if err := this.loadAllPages(); err != nil {
+			return 0, err
+		}
+	}
+
+	this.pagesMu.Lock()
+	defer this.pagesMu.Unlock()
+
+	if this.pageNumbers == nil {
+		this.pageNumbers = make(map[int]int, len(this.pages))
+		for i, page := range this.pages {
+			this.pageNumbers[page.page.Id] = i + 1
+		}
+	}
+
+	return this.pageNumbers[id], nil
+}
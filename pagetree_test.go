@@ -0,0 +1,173 @@
+package gofpdi
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// Check the object ids of the pages of a document
+func checkPageIds(t *testing.T, reader *PdfReader, ids ...int) {
+	t.Helper()
+
+	if reader.NumPages() != len(ids) {
+		t.Fatalf("%d pages, want %d", reader.NumPages(), len(ids))
+	}
+
+	for i, id := range ids {
+		page, err := reader.Page(i + 1)
+		if err != nil {
+			t.Fatalf("page %d: %v", i+1, err)
+		}
+		if page.Id != id {
+			t.Errorf("page %d is object %d, want %d", i+1, page.Id, id)
+		}
+	}
+}
+
+func TestRandomPageAccess(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 5 /MediaBox [0 0 612 792] >>",
+		"<< /Type /Pages /Parent 2 0 R /Kids [5 0 R 6 0 R 7 0 R] /Count 3 >>",
+		"<< /Type /Pages /Parent 2 0 R /Kids [8 0 R 9 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 3 0 R >>",
+		"<< /Type /Page /Parent 3 0 R >>",
+		"<< /Type /Page /Parent 3 0 R >>",
+		"<< /Type /Page /Parent 4 0 R >>",
+		"<< /Type /Page /Parent 4 0 R >>",
+	)
+
+	// Descending by /Count only resolves the nodes on the way to the page
+	page, err := reader.Page(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Id != 9 {
+		t.Errorf("page 5 is object %d, want 9", page.Id)
+	}
+	if reader.pagesWalked {
+		t.Error("the whole page tree was walked")
+	}
+	for _, id := range []int{5, 6, 7} {
+		if _, ok := reader.cache.entries[cacheKey{id: id}]; ok {
+			t.Errorf("page object %d of another subtree was read", id)
+		}
+	}
+
+	checkPageIds(t, reader, 5, 6, 7, 8, 9)
+}
+
+func TestPageTreeCountMismatch(t *testing.T) {
+	for _, count := range []string{"2", "7", "(x)"} {
+		reader := readTestPDF(t,
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count "+count+" >>",
+			"<< /Type /Pages /Parent 2 0 R /Kids [5 0 R 6 0 R] /Count 2 >>",
+			"<< /Type /Page /Parent 2 0 R >>",
+			"<< /Type /Page /Parent 3 0 R >>",
+			"<< /Type /Page /Parent 3 0 R >>",
+		)
+
+		// The page count is corrected once a page is looked up
+		if _, err := reader.Page(1); err != nil {
+			t.Fatalf("/Count %s: %v", count, err)
+		}
+		checkPageIds(t, reader, 5, 6, 4)
+	}
+}
+
+func TestPageTreeCycle(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 3 >>",
+		"<< /Type /Pages /Parent 2 0 R /Kids [5 0 R 2 0 R 3 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /Type /Page /Parent 3 0 R >>",
+	)
+
+	if _, err := reader.Page(2); err != nil {
+		t.Fatal(err)
+	}
+	checkPageIds(t, reader, 5, 4)
+
+	if _, err := reader.Page(3); !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("Page(3) = %v, want ErrPageOutOfRange", err)
+	}
+}
+
+func TestInheritedPageAttributes(t *testing.T) {
+	reader := readTestPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 2 /MediaBox [0 0 612 792] /Rotate 90 /Resources << >> >>",
+		"<< /Type /Pages /Parent 2 0 R /Kids [4 0 R 5 0 R] /Count 2 /MediaBox [0 0 100 200] >>",
+		"<< /Type /Page /Parent 3 0 R >>",
+		"<< /Type /Page /Parent 3 0 R /MediaBox [0 0 50 50] /Rotate 0 >>",
+	)
+
+	tests := []struct {
+		pageno int
+		width  float64
+		rotate int
+	}{
+		{1, 100, 90},
+		{2, 50, 0},
+	}
+
+	for _, test := range tests {
+		page, err := reader.getPageNode(test.pageno)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mediaBox, ok := page.attribute("/MediaBox")
+		if !ok || len(mediaBox.Array) != 4 || mediaBox.Array[2].Int != int(test.width) {
+			t.Errorf("page %d /MediaBox %v, want a width of %v", test.pageno, mediaBox, test.width)
+		}
+		rotate, _ := page.attribute("/Rotate")
+		if rotate == nil || rotate.Int != test.rotate {
+			t.Errorf("page %d /Rotate %v, want %d", test.pageno, rotate, test.rotate)
+		}
+		if _, ok := page.attribute("/Resources"); !ok {
+			t.Errorf("page %d does not inherit /Resources", test.pageno)
+		}
+	}
+}
+
+func TestPageTreeWalkedConcurrently(t *testing.T) {
+	// Looking up a page and walking the whole tree (for page numbers) at the same time, while the page count
+	// shrinks from /Count to the pages actually found
+	for n := 0; n < 20; n++ {
+		reader := readTestPDF(t,
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 9 >>",
+			"<< /Type /Page /Parent 2 0 R >>",
+			"<< /Type /Page /Parent 2 0 R >>",
+		)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for pageno := 1; pageno <= 9; pageno++ {
+			wg.Add(1)
+			go func(pageno int) {
+				defer wg.Done()
+				if _, err := reader.Page(pageno); err != nil && (pageno <= 2 || !errors.Is(err, ErrPageOutOfRange)) {
+					errs <- err
+				}
+			}(pageno)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := reader.pageNumber(3); err != nil {
+				errs <- err
+			}
+		}()
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Error(err)
+		}
+	}
+}
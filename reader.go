@@ -3,43 +3,117 @@ package gofpdi
 import (
 	"bufio"
 	"bytes"
-	"compress/zlib"
-	"encoding/binary"
 	"fmt"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/tim-timpani/gofpdi/text"
 	"io"
-	"io/ioutil"
-	"math"
 	"os"
 	"regexp"
 	"strconv"
+	"sync"
 )
 
 type PdfReader struct {
 	availableBoxes []string
-	stack          []string
 	trailer        *PdfValue
 	catalog        *PdfValue
-	pages          []*PdfValue
+	pages          []*pageNode
+	pagesRoot      *PdfValue
+	pagesWalked    bool
+	pageNumbers    map[int]int
+	pageObjects    map[int]int
+	pagesMu        sync.Mutex
 	xrefPos        int
+	startXref      int
 	xref           map[int]map[int]int
 	xrefStream     map[int][2]int
-	f              io.ReadSeeker
+	f              io.ReaderAt
 	nBytes         int64
 	sourceFile     string
-	curPage        int
 	alreadyRead    bool
 	pageCount      int
+	password       string
+	crypt          *pdfCrypt
+	repairXref     bool
+	repairObjStms  []repairObject
+	xrefVisited    map[int]bool
+	xrefFree       map[int]bool
+	cache          *objectCache
+	cacheSize      int64
 }
 
-func NewPdfReaderFromStream(rs io.ReadSeeker) (*PdfReader, error) {
+// ReaderOption configures optional behavior of a PdfReader
+type ReaderOption func(*PdfReader)
+
+// WithPassword sets the user or owner password used to open encrypted PDFs
+func WithPassword(password string) ReaderOption {
+	return func(r *PdfReader) {
+		r.password = password
+	}
+}
+
+// WithXrefRepair enables recovery of damaged PDFs.  If the cross-reference table cannot be read
+// (e.g. startxref is missing or its offsets are wrong), it is rebuilt by scanning the whole file.
+func WithXrefRepair() ReaderOption {
+	return func(r *PdfReader) {
+		r.repairXref = true
+	}
+}
+
+// A buffered reader with its own token pushback stack, so that concurrent parses don't share any state
+type tokenReader struct {
+	*bufio.Reader
+	stack []string
+}
+
+func newTokenReader(r io.Reader) *tokenReader {
+	return &tokenReader{Reader: bufio.NewReader(r)}
+}
+
+// Adapts an io.ReadSeeker that is not an io.ReaderAt; reads are serialized with a mutex
+type readSeekerAt struct {
+	rs io.ReadSeeker
+	mu sync.Mutex
+}
+
+func (this *readSeekerAt) ReadAt(p []byte, off int64) (int, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	_, err := this.rs.Seek(off, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(this.rs, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func NewPdfReaderFromStream(rs io.ReadSeeker, opts ...ReaderOption) (*PdfReader, error) {
 	length, err := rs.Seek(0, 2)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to determine stream length")
 	}
-	parser := &PdfReader{f: rs, nBytes: length}
+
+	// Read with positional reads, so that the document can be used from several goroutines
+	ra, ok := rs.(io.ReaderAt)
+	if !ok {
+		ra = &readSeekerAt{rs: rs}
+	}
+
+	return NewPdfReaderFromReaderAt(ra, length, opts...)
+}
+
+// Create a reader for a PDF of size bytes.  Once created, the reader may be used from several goroutines.
+func NewPdfReaderFromReaderAt(ra io.ReaderAt, size int64, opts ...ReaderOption) (*PdfReader, error) {
+	parser := &PdfReader{f: ra, nBytes: size, cacheSize: defaultObjectCacheSize}
+	for _, opt := range opts {
+		opt(parser)
+	}
 	if err := parser.init(); err != nil {
 		return nil, errors.Wrap(err, "Failed to initialize parser")
 	}
@@ -49,7 +123,7 @@ func NewPdfReaderFromStream(rs io.ReadSeeker) (*PdfReader, error) {
 	return parser, nil
 }
 
-func NewPdfReader(filename string) (*PdfReader, error) {
+func NewPdfReader(filename string, opts ...ReaderOption) (*PdfReader, error) {
 	var err error
 	f, err := os.Open(filename)
 	if err != nil {
@@ -60,7 +134,10 @@ func NewPdfReader(filename string) (*PdfReader, error) {
 		return nil, errors.Wrap(err, "Failed to obtain file information")
 	}
 
-	parser := &PdfReader{f: f, sourceFile: filename, nBytes: info.Size()}
+	parser := &PdfReader{f: f, sourceFile: filename, nBytes: info.Size(), cacheSize: defaultObjectCacheSize}
+	for _, opt := range opts {
+		opt(parser)
+	}
 	if err = parser.init(); err != nil {
 		return nil, errors.Wrap(err, "Failed to initialize parser")
 	}
@@ -71,10 +148,16 @@ func NewPdfReader(filename string) (*PdfReader, error) {
 	return parser, nil
 }
 
+// Get a reader starting at an offset of the file
+func (this *PdfReader) readerAt(offset int64) *tokenReader {
+	if offset < 0 || offset > this.nBytes {
+		offset = this.nBytes
+	}
+	return newTokenReader(io.NewSectionReader(this.f, offset, this.nBytes-offset))
+}
+
 func (this *PdfReader) init() error {
 	this.availableBoxes = []string{"/MediaBox", "/CropBox", "/BleedBox", "/TrimBox", "/ArtBox"}
-	this.xref = make(map[int]map[int]int, 0)
-	this.xrefStream = make(map[int][2]int, 0)
 	err := this.read()
 	if err != nil {
 		return errors.Wrap(err, "Failed to read pdf")
@@ -100,7 +183,7 @@ type PdfValue struct {
 }
 
 // Jump over comments
-func (this *PdfReader) skipComments(r *bufio.Reader) error {
+func (this *PdfReader) skipComments(r *tokenReader) error {
 	var err error
 	var b byte
 
@@ -129,7 +212,7 @@ func (this *PdfReader) skipComments(r *bufio.Reader) error {
 }
 
 // Advance reader so that whitespace is ignored
-func (this *PdfReader) skipWhitespace(r *bufio.Reader) error {
+func (this *PdfReader) skipWhitespace(r *tokenReader) error {
 	var err error
 	var b byte
 
@@ -153,14 +236,45 @@ func (this *PdfReader) skipWhitespace(r *bufio.Reader) error {
 	return nil
 }
 
+// Check the length of a stream in the object at offset, so that a corrupt /Length is not allocated.  The stream
+// data cannot extend past the end of the file.
+func (this *PdfReader) checkStreamLength(length int, offset int64) error {
+	if length < 0 || int64(length) > this.nBytes-offset {
+		return errors.New(fmt.Sprintf("Invalid stream length: %d", length))
+	}
+
+	return nil
+}
+
+// Skip the end-of-line marker after the stream keyword.  Only CRLF or LF may follow it (a lone CR is tolerated),
+// as the stream data itself may start with whitespace, e.g. encrypted data.
+func (this *PdfReader) skipStreamEOL(r *tokenReader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return errors.Wrap(err, "Failed to read byte")
+	}
+
+	if b == '\r' {
+		b, err = r.ReadByte()
+		if err != nil {
+			return errors.Wrap(err, "Failed to read byte")
+		}
+	}
+	if b != '\n' {
+		r.UnreadByte()
+	}
+
+	return nil
+}
+
 // Read a token
-func (this *PdfReader) readToken(r *bufio.Reader) (string, error) {
+func (this *PdfReader) readToken(r *tokenReader) (string, error) {
 	var err error
 
 	// If there is a token available on the stack, pop it out and return it.
-	if len(this.stack) > 0 {
+	if len(r.stack) > 0 {
 		var popped string
-		popped, this.stack = this.stack[len(this.stack)-1], this.stack[:len(this.stack)-1]
+		popped, r.stack = r.stack[len(r.stack)-1], r.stack[:len(r.stack)-1]
 		return popped, nil
 	}
 
@@ -224,12 +338,10 @@ func (this *PdfReader) readToken(r *bufio.Reader) (string, error) {
 		}
 		return str, nil
 	}
-
-	return "", nil
 }
 
 // Read a value based on a token
-func (this *PdfReader) readValue(r *bufio.Reader, t string) (*PdfValue, error) {
+func (this *PdfReader) readValue(r *tokenReader, t string) (*PdfValue, error) {
 	var err error
 	var b byte
 
@@ -424,11 +536,11 @@ func (this *PdfReader) readValue(r *bufio.Reader, t string) (*PdfValue, error) {
 
 						// If we get to this point, that numeric value up there was just a numeric value.
 						// Push the extra tokens back into the stack and return the value.
-						this.stack = append(this.stack, t3)
+						r.stack = append(r.stack, t3)
 					}
 				}
 
-				this.stack = append(this.stack, t2)
+				r.stack = append(r.stack, t2)
 			}
 
 			if n, err := strconv.Atoi(t); err == nil {
@@ -455,150 +567,178 @@ func (this *PdfReader) readValue(r *bufio.Reader, t string) (*PdfValue, error) {
 
 // Resolve a compressed object (PDF 1.5)
 func (this *PdfReader) resolveCompressedObject(objSpec *PdfValue) (*PdfValue, error) {
-	var err error
-
 	// Make sure object reference exists in xrefStream
 	if _, ok := this.xrefStream[objSpec.Id]; !ok {
-		return nil, errors.New(fmt.Sprintf("Could not find object ID %d in xref stream or xref table.", objSpec.Id))
+		return nil, ErrObjectNotFound
 	}
 
 	// Get object id and index
 	objectId := this.xrefStream[objSpec.Id][0]
 	objectIndex := this.xrefStream[objSpec.Id][1]
 
-	// Read compressed object
-	compressedObjSpec := &PdfValue{Type: PDF_TYPE_OBJREF, Id: objectId, Gen: 0}
+	// Get the decoded object stream
+	stm, err := this.getObjectStream(objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	if objectIndex < 0 || objectIndex >= len(stm.ids) {
+		return nil, errors.New(fmt.Sprintf("Index %d of object %d is out of range of object stream %d", objectIndex, objSpec.Id, objectId))
+	}
+
+	// Determine where the object starts (sub-object position + /First)
+	start := stm.first + stm.offsets[objectIndex]
+	if start < 0 || start > len(stm.data) {
+		return nil, errors.New(fmt.Sprintf("Invalid position of object %d in object stream: %d", objSpec.Id, start))
+	}
+
+	// Create a new reader starting at the object
+	r := newTokenReader(bytes.NewReader(stm.data[start:]))
+
+	// Read token
+	token, err := this.readToken(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read token")
+	}
+
+	// Read object
+	obj, err := this.readValue(r, token)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read value for token: "+token)
+	}
+
+	result := &PdfValue{}
+	result.Id = stm.ids[objectIndex]
+	result.Gen = 0
+	result.Type = PDF_TYPE_OBJECT
+	result.Value = obj
+
+	return result, nil
+}
+
+// Get a decoded object stream (/ObjStm) along with the ids and positions of the objects it contains.
+// Decoded object streams are cached, so that the stream is inflated only once for all of its objects.
+func (this *PdfReader) getObjectStream(id int) (*objectStream, error) {
+	key := cacheKey{id: id, objStm: true}
+	if cached, ok := this.cache.get(key); ok {
+		return cached.(*objectStream), nil
+	}
+
+	// Object streams cannot be compressed themselves; following such an entry would recurse forever
+	if _, ok := this.xref[id]; !ok {
+		return nil, errors.New(fmt.Sprintf("Object stream %d is not an uncompressed object", id))
+	}
 
 	// Resolve compressed object
-	compressedObj, err := this.resolveObject(compressedObjSpec)
+	compressedObj, err := this.resolveObject(&PdfValue{Type: PDF_TYPE_OBJREF, Id: id, Gen: 0})
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to resolve compressed object")
 	}
 
 	// Verify object type is /ObjStm
-	if _, ok := compressedObj.Value.Dictionary["/Type"]; ok {
-		if compressedObj.Value.Dictionary["/Type"].Token != "/ObjStm" {
-			return nil, errors.New("Expected compressed object type to be /ObjStm")
-		}
-	} else {
-		return nil, errors.New("Could not determine compressed object type.")
+	if t, ok := compressedObj.Value.Dictionary["/Type"]; !ok || t.Token != "/ObjStm" {
+		return nil, errors.New("Expected compressed object type to be /ObjStm")
 	}
 
 	// Get number of sub-objects in compressed object
-	n := compressedObj.Value.Dictionary["/N"].Int
+	n := 0
+	if v, ok := compressedObj.Value.Dictionary["/N"]; ok {
+		n = v.Int
+	}
 	if n <= 0 {
 		return nil, errors.New("No sub objects in compressed object")
 	}
 
 	// Get offset of first object
-	first := compressedObj.Value.Dictionary["/First"].Int
-
-	// Get length
-	//length := compressedObj.Value.Dictionary["/Length"].Int
-
-	// Check for filter
-	filter := ""
-	if _, ok := compressedObj.Value.Dictionary["/Filter"]; ok {
-		filter = compressedObj.Value.Dictionary["/Filter"].Token
-		if filter != "/FlateDecode" {
-			return nil, errors.New("Unsupported filter - expected /FlateDecode, got: " + filter)
-		}
+	first := 0
+	if v, ok := compressedObj.Value.Dictionary["/First"]; ok {
+		first = v.Int
 	}
 
-	if filter == "/FlateDecode" {
-		// Decompress if filter is /FlateDecode
-		// Uncompress zlib compressed data
-		var out bytes.Buffer
-		zlibReader, _ := zlib.NewReader(bytes.NewBuffer(compressedObj.Stream.Bytes))
-		defer zlibReader.Close()
-		io.Copy(&out, zlibReader)
+	// Decode stream data
+	data, err := this.decodeStream(compressedObj)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode compressed object stream")
+	}
 
-		// Set stream to uncompressed data
-		compressedObj.Stream.Bytes = out.Bytes()
+	// Each object takes at least a byte of the stream, which bounds the capacity allocated for them
+	if n > len(data) {
+		return nil, errors.New(fmt.Sprintf("Invalid number of objects in object stream: %d", n))
 	}
 
-	// Get io.Reader for bytes
-	r := bufio.NewReader(bytes.NewBuffer(compressedObj.Stream.Bytes))
+	stm := &objectStream{data: data, first: first, ids: make([]int, 0, n), offsets: make([]int, 0, n)}
 
-	subObjId := 0
-	subObjPos := 0
+	// Get io.Reader for bytes
+	r := newTokenReader(bytes.NewReader(data))
 
-	// Read sub-object indeces and their positions within the (un)compressed object
+	// Read sub-object ids and their positions within the (un)compressed object
 	for i := 0; i < n; i++ {
-		var token string
-		var _objidx int
-		var _objpos int
-
-		// Read first token (object index)
-		token, err = this.readToken(r)
+		// Read first token (object id)
+		token, err := this.readToken(r)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to read token")
 		}
 
 		// Convert line (string) into int
-		_objidx, err = strconv.Atoi(token)
+		objId, err := strconv.Atoi(token)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to convert token into integer: "+token)
 		}
 
-		// Read first token (object index)
+		// Read second token (object position)
 		token, err = this.readToken(r)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to read token")
 		}
 
 		// Convert line (string) into int
-		_objpos, err = strconv.Atoi(token)
+		objPos, err := strconv.Atoi(token)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to convert token into integer: "+token)
 		}
 
-		if i == objectIndex {
-			subObjId = _objidx
-			subObjPos = _objpos
-		}
+		stm.ids = append(stm.ids, objId)
+		stm.offsets = append(stm.offsets, objPos)
 	}
 
-	// Now create an io.ReadSeeker
-	rs := io.ReadSeeker(bytes.NewReader(compressedObj.Stream.Bytes))
+	this.cache.put(key, stm, stm.size())
 
-	// Determine where to seek to (sub-object position + /First)
-	seekTo := int64(subObjPos + first)
-
-	// Fast forward to the object
-	rs.Seek(seekTo, 0)
+	return stm, nil
+}
 
-	// Create a new io.Reader
-	r = bufio.NewReader(rs)
+// Resolve an object reference, or return any other value as is.  Failures are reported as an *ObjectError.
+func (this *PdfReader) resolveObject(objSpec *PdfValue) (*PdfValue, error) {
+	if objSpec.Type != PDF_TYPE_OBJREF {
+		return objSpec, nil
+	}
 
-	// Read token
-	token, err := this.readToken(r)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to read token")
+	// Parsed objects are cached and shared, callers must not modify them
+	key := cacheKey{id: objSpec.Id, gen: objSpec.Gen}
+	if cached, ok := this.cache.get(key); ok {
+		return cached.(*PdfValue), nil
 	}
 
-	// Read object
-	obj, err := this.readValue(r, token)
+	result, err := this.readObject(objSpec)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to read value for token: "+token)
+		// Keep the error of a nested object (e.g. an indirect stream /Length) as is
+		var objErr *ObjectError
+		if errors.As(err, &objErr) {
+			return nil, err
+		}
+
+		offset := int64(-1)
+		if pos, ok := this.xref[objSpec.Id][objSpec.Gen]; ok {
+			offset = int64(pos)
+		}
+		return nil, &ObjectError{Id: objSpec.Id, Gen: objSpec.Gen, Offset: offset, Err: err}
 	}
 
-	result := &PdfValue{}
-	result.Id = subObjId
-	result.Gen = 0
-	result.Type = PDF_TYPE_OBJECT
-	result.Value = obj
+	this.cache.put(key, result, pdfValueSize(result))
 
 	return result, nil
 }
 
-func (this *PdfReader) resolveObject(objSpec *PdfValue) (*PdfValue, error) {
-	var err error
-	var old_pos int64
-
-	// Create new bufio.Reader
-	r := bufio.NewReader(this.f)
-
+func (this *PdfReader) readObject(objSpec *PdfValue) (*PdfValue, error) {
 	if objSpec.Type == PDF_TYPE_OBJREF {
 		// This is a reference, resolve it.
 		offset := this.xref[objSpec.Id][objSpec.Gen]
@@ -608,19 +748,9 @@ func (this *PdfReader) resolveObject(objSpec *PdfValue) (*PdfValue, error) {
 			return this.resolveCompressedObject(objSpec)
 		}
 
-		// Save current file position
-		// This is needed if you want to resolve reference while you're reading another object.
-		// (e.g.: if you need to determine the length of a stream)
-		old_pos, err = this.f.Seek(0, os.SEEK_CUR)
-		if err != nil {
-			return nil, errors.Wrap(err, "Failed to get current position of file")
-		}
-
-		// Reposition the file pointer and load the object header
-		_, err = this.f.Seek(int64(offset), 0)
-		if err != nil {
-			return nil, errors.Wrap(err, "Failed to set position of file")
-		}
+		// Load the object header.  Every object gets its own reader, so references can be resolved
+		// while another object is being read (e.g. to determine the length of a stream).
+		r := this.readerAt(int64(offset))
 
 		token, err := this.readToken(r)
 		if err != nil {
@@ -633,15 +763,15 @@ func (this *PdfReader) resolveObject(objSpec *PdfValue) (*PdfValue, error) {
 		}
 
 		if obj.Type != PDF_TYPE_OBJDEC {
-			return nil, errors.New(fmt.Sprintf("Expected type to be PDF_TYPE_OBJDEC, got: %d", obj.Type))
+			return nil, &XrefError{Offset: int64(offset), Err: errors.New(fmt.Sprintf("Expected type to be PDF_TYPE_OBJDEC, got: %d", obj.Type))}
 		}
 
 		if obj.Id != objSpec.Id {
-			return nil, errors.New(fmt.Sprintf("Object ID (%d) does not match ObjSpec ID (%d)", obj.Id, objSpec.Id))
+			return nil, &XrefError{Offset: int64(offset), Err: errors.New(fmt.Sprintf("Object ID (%d) does not match ObjSpec ID (%d)", obj.Id, objSpec.Id))}
 		}
 
 		if obj.Gen != objSpec.Gen {
-			return nil, errors.New("Object Gen does not match ObjSpec Gen")
+			return nil, &XrefError{Offset: int64(offset), Err: errors.New("Object Gen does not match ObjSpec Gen")}
 		}
 
 		// Read next token
@@ -671,13 +801,16 @@ func (this *PdfReader) resolveObject(objSpec *PdfValue) (*PdfValue, error) {
 		if token == "stream" {
 			result.Type = PDF_TYPE_STREAM
 
-			err = this.skipWhitespace(r)
+			err = this.skipStreamEOL(r)
 			if err != nil {
-				return nil, errors.Wrap(err, "Failed to skip whitespace")
+				return nil, errors.Wrap(err, "Failed to skip end of line")
 			}
 
 			// Get stream length dictionary
-			lengthDict := value.Dictionary["/Length"]
+			lengthDict, ok := value.Dictionary["/Length"]
+			if !ok {
+				return nil, errors.New("Stream has no /Length")
+			}
 
 			// Get number of bytes of stream
 			length := lengthDict.Int
@@ -690,10 +823,19 @@ func (this *PdfReader) resolveObject(objSpec *PdfValue) (*PdfValue, error) {
 					return nil, errors.Wrap(err, "Failed to resolve length object of stream")
 				}
 
+				if lengthDict.Value == nil {
+					return nil, errors.New("Invalid length object of stream")
+				}
+
 				// Set length to resolved object value
 				length = lengthDict.Value.Int
 			}
 
+			err = this.checkStreamLength(length, int64(offset))
+			if err != nil {
+				return nil, err
+			}
+
 			// Read length bytes
 			bytes := make([]byte, length)
 
@@ -727,25 +869,25 @@ func (this *PdfReader) resolveObject(objSpec *PdfValue) (*PdfValue, error) {
 			return nil, errors.New("Expected next token to be: endobj, got: " + token)
 		}
 
-		// Reposition the file pointer to previous position
-		_, err = this.f.Seek(old_pos, 0)
-		if err != nil {
-			return nil, errors.Wrap(err, "Failed to set position of file")
+		// Decrypt strings and stream data of encrypted documents
+		if this.crypt != nil {
+			err = this.crypt.decryptObject(result)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to decrypt object")
+			}
 		}
 
 		return result, nil
 
-	} else {
-		return objSpec, nil
 	}
 
-	return &PdfValue{}, nil
+	return objSpec, nil
 }
 
 // Find the xref offset (should be at the end of the PDF)
 func (this *PdfReader) findXref() error {
 	var result int
-	var err error
+	var found bool
 	var toRead int64
 
 	toRead = 1500
@@ -756,26 +898,22 @@ func (this *PdfReader) findXref() error {
 		toRead = fileSize
 	}
 
-	// 0 means relative to the origin of the file,
-	// 1 means relative to the current offset,
-	// and 2 means relative to the end.
-	whence := 2
-
-	// Perform seek operation
-	_, err = this.f.Seek(-toRead, whence)
-	if err != nil {
-		return errors.Wrap(err, "Failed to set position of file")
-	}
-
-	// Create new bufio.Reader
-	r := bufio.NewReader(this.f)
+	// Read the end of the file
+	r := this.readerAt(fileSize - toRead)
 	for {
-		// Read all tokens until "startxref" is found
+		// Read all tokens until the end of the file; the last "startxref" belongs to the newest incremental update
 		token, err := this.readToken(r)
 		if err != nil {
 			return errors.Wrap(err, "Failed to read token")
 		}
 
+		if token == "" {
+			if !found {
+				return errors.New("Could not find startxref")
+			}
+			break
+		}
+
 		if token == "startxref" {
 			token, err = this.readToken(r)
 			// Probably EOF before finding startxref
@@ -790,18 +928,10 @@ func (this *PdfReader) findXref() error {
 			}
 
 			// Successfully read the xref position
-			this.xrefPos = result
-			break
+			found = true
 		}
 	}
 
-	// Rewind file pointer
-	whence = 0
-	_, err = this.f.Seek(0, whence)
-	if err != nil {
-		return errors.Wrap(err, "Failed to set position of file")
-	}
-
 	this.xrefPos = result
 
 	return nil
@@ -811,14 +941,14 @@ func (this *PdfReader) findXref() error {
 func (this *PdfReader) readXref() error {
 	var err error
 
-	// Create new bufio.Reader
-	r := bufio.NewReader(this.f)
-
-	// Set file pointer to xref start
-	_, err = this.f.Seek(int64(this.xrefPos), 0)
-	if err != nil {
-		return errors.Wrap(err, "Failed to set position of file")
+	// Guard against /Prev chains that loop back to an xref section that was already read
+	if this.xrefVisited[this.xrefPos] {
+		return nil
 	}
+	this.xrefVisited[this.xrefPos] = true
+
+	// Read from the xref start
+	r := this.readerAt(int64(this.xrefPos))
 
 	// Xref should start with 'xref'
 	t, err := this.readToken(r)
@@ -827,256 +957,29 @@ func (this *PdfReader) readXref() error {
 	}
 	if t != "xref" {
 		// Maybe this is an XRef stream ...
-		v, err := this.readValue(r, t)
+		v, err := this.readXrefStream(r, t, int64(this.xrefPos))
 		if err != nil {
-			return errors.Wrap(err, "Failed to read XRef stream")
+			return err
 		}
 
-		if v.Type == PDF_TYPE_OBJDEC {
-			// Read next token
-			t, err = this.readToken(r)
-			if err != nil {
-				return errors.Wrap(err, "Failed to read token")
-			}
+		// Check for previous xref stream
+		if prev, ok := v.Dictionary["/Prev"]; ok && prev.Int > 0 {
+			// Set xrefPos to /Prev xref
+			this.xrefPos = prev.Int
 
-			// Read actual object value
-			v, err := this.readValue(r, t)
-			if err != nil {
-				return errors.Wrap(err, "Failed to read value for token: "+t)
+			// Read previous xref
+			xrefErr := this.readXref()
+			if xrefErr != nil {
+				return errors.Wrap(xrefErr, "Failed to read prev xref")
 			}
-
-			// If /Type is set, check to see if it is XRef
-			if _, ok := v.Dictionary["/Type"]; ok {
-				if v.Dictionary["/Type"].Token == "/XRef" {
-					// Continue reading xref stream data now that it is confirmed that it is an xref stream
-
-					// Check for /DecodeParms
-					paethDecode := false
-					if _, ok := v.Dictionary["/DecodeParms"]; ok {
-						columns := 0
-						predictor := 0
-
-						if _, ok2 := v.Dictionary["/DecodeParms"].Dictionary["/Columns"]; ok2 {
-							columns = v.Dictionary["/DecodeParms"].Dictionary["/Columns"].Int
-						}
-						if _, ok2 := v.Dictionary["/DecodeParms"].Dictionary["/Predictor"]; ok2 {
-							predictor = v.Dictionary["/DecodeParms"].Dictionary["/Predictor"].Int
-						}
-
-						if columns > 4 || predictor > 12 {
-							return errors.New("Unsupported /DecodeParms - only tested with /Columns <= 4 and /Predictor <= 12")
-						}
-						paethDecode = true
-					}
-
-					/*
-						// Check to make sure field size is [1 2 1] - not yet tested with other field sizes
-						if v.Dictionary["/W"].Array[0].Int != 1 || v.Dictionary["/W"].Array[1].Int > 4 || v.Dictionary["/W"].Array[2].Int != 1 {
-							return errors.New(fmt.Sprintf("Unsupported field sizes in cross-reference stream dictionary: /W [%d %d %d]",
-								v.Dictionary["/W"].Array[0].Int,
-								v.Dictionary["/W"].Array[1].Int,
-								v.Dictionary["/W"].Array[2].Int))
-						}
-					*/
-
-					index := make([]int, 2)
-
-					// If /Index is not set, this is an error
-					if _, ok := v.Dictionary["/Index"]; ok {
-						if len(v.Dictionary["/Index"].Array) < 2 {
-							return errors.Wrap(err, "Index array does not contain 2 elements")
-						}
-
-						index[0] = v.Dictionary["/Index"].Array[0].Int
-						index[1] = v.Dictionary["/Index"].Array[1].Int
-					} else {
-						index[0] = 0
-					}
-
-					prevXref := 0
-
-					// Check for previous xref stream
-					if _, ok := v.Dictionary["/Prev"]; ok {
-						prevXref = v.Dictionary["/Prev"].Int
-					}
-
-					// Set root object
-					if _, ok := v.Dictionary["/Root"]; ok {
-						// Just set the whole dictionary with /Root key to keep compatibiltiy with existing code
-						this.trailer = v
-					} else {
-						// Don't return an error here.  The trailer could be in another XRef stream.
-						//return errors.New("Did not set root object")
-					}
-
-					startObject := index[0]
-
-					err = this.skipWhitespace(r)
-					if err != nil {
-						return errors.Wrap(err, "Failed to skip whitespace")
-					}
-
-					// Get stream length dictionary
-					lengthDict := v.Dictionary["/Length"]
-
-					// Get number of bytes of stream
-					length := lengthDict.Int
-
-					// If lengthDict is an object reference, resolve the object and set length
-					if lengthDict.Type == PDF_TYPE_OBJREF {
-						lengthDict, err = this.resolveObject(lengthDict)
-
-						if err != nil {
-							return errors.Wrap(err, "Failed to resolve length object of stream")
-						}
-
-						// Set length to resolved object value
-						length = lengthDict.Value.Int
-					}
-
-					t, err = this.readToken(r)
-					if err != nil {
-						return errors.Wrap(err, "Failed to read token")
-					}
-					if t != "stream" {
-						return errors.New("Expected next token to be: stream, got: " + t)
-					}
-
-					err = this.skipWhitespace(r)
-					if err != nil {
-						return errors.Wrap(err, "Failed to skip whitespace")
-					}
-
-					// Read length bytes
-					data := make([]byte, length)
-
-					// Cannot use reader.Read() because that may not read all the bytes
-					_, err := io.ReadFull(r, data)
-					if err != nil {
-						return errors.Wrap(err, "Failed to read bytes from buffer")
-					}
-
-					// Look for endstream token
-					t, err = this.readToken(r)
-					if err != nil {
-						return errors.Wrap(err, "Failed to read token")
-					}
-					if t != "endstream" {
-						return errors.New("Expected next token to be: endstream, got: " + t)
-					}
-
-					// Look for endobj token
-					t, err = this.readToken(r)
-					if err != nil {
-						return errors.Wrap(err, "Failed to read token")
-					}
-					if t != "endobj" {
-						return errors.New("Expected next token to be: endobj, got: " + t)
-					}
-
-					// Now decode zlib data
-					b := bytes.NewReader(data)
-
-					z, err := zlib.NewReader(b)
-					if err != nil {
-						return errors.Wrap(err, "zlib.NewReader error")
-					}
-					defer z.Close()
-
-					p, err := ioutil.ReadAll(z)
-					if err != nil {
-						return errors.Wrap(err, "ioutil.ReadAll error")
-					}
-
-					objPos := 0
-					objGen := 0
-					i := startObject
-
-					// Decode result with paeth algorithm
-					var result []byte
-					b = bytes.NewReader(p)
-
-					firstFieldSize := v.Dictionary["/W"].Array[0].Int
-					middleFieldSize := v.Dictionary["/W"].Array[1].Int
-					lastFieldSize := v.Dictionary["/W"].Array[2].Int
-
-					fieldSize := firstFieldSize + middleFieldSize + lastFieldSize
-					if paethDecode {
-						fieldSize++
-					}
-
-					prevRow := make([]byte, fieldSize)
-					for {
-						result = make([]byte, fieldSize)
-						_, err := io.ReadFull(b, result)
-						if err != nil {
-							if err == io.EOF {
-								break
-							} else {
-								return errors.Wrap(err, "io.ReadFull error")
-							}
-						}
-
-						if paethDecode {
-							filterPaeth(result, prevRow, fieldSize)
-							copy(prevRow, result)
-						}
-
-						objectData := make([]byte, fieldSize)
-						if paethDecode {
-							copy(objectData, result[1:fieldSize])
-						} else {
-							copy(objectData, result[0:fieldSize])
-						}
-
-						if objectData[0] == 1 {
-							// Regular objects
-							b := make([]byte, 4)
-							copy(b[4-middleFieldSize:], objectData[1:1+middleFieldSize])
-
-							objPos = int(binary.BigEndian.Uint32(b))
-							objGen = int(objectData[firstFieldSize+middleFieldSize])
-
-							// Append map[int]int
-							this.xref[i] = make(map[int]int, 1)
-
-							// Set object id, generation, and position
-							this.xref[i][objGen] = objPos
-						} else if objectData[0] == 2 {
-							// Compressed objects
-							b := make([]byte, 4)
-							copy(b[4-middleFieldSize:], objectData[1:1+middleFieldSize])
-
-							objId := int(binary.BigEndian.Uint32(b))
-							objIdx := int(objectData[firstFieldSize+middleFieldSize])
-
-							// object id (i) is located in StmObj (objId) at index (objIdx)
-							this.xrefStream[i] = [2]int{objId, objIdx}
-						}
-
-						i++
-					}
-
-					// Check for previous xref stream
-					if prevXref > 0 {
-						// Set xrefPos to /Prev xref
-						this.xrefPos = prevXref
-
-						// Read preivous xref
-						xrefErr := this.readXref()
-						if xrefErr != nil {
-							return errors.Wrap(xrefErr, "Failed to read prev xref")
-						}
-					}
-				}
-			}
-
-			return nil
 		}
 
-		return errors.New("Expected xref to start with 'xref'.  Got: " + t)
+		return nil
 	}
 
+	// Free entries of this section, claimed only after the /XRefStm of a hybrid file has been read
+	free := make([]int, 0)
+
 	for {
 		// Next value will be the starting object id (usually 0, but not always) or the trailer
 		t, err = this.readToken(r)
@@ -1140,11 +1043,13 @@ func (this *PdfReader) readXref() error {
 				return errors.New("Expected objStatus to be 'n' or 'f', got: " + objStatus)
 			}
 
-			// Append map[int]int
-			this.xref[i] = make(map[int]int, 1)
+			if objStatus == "f" {
+				free = append(free, i)
+				continue
+			}
 
 			// Set object id, generation, and position
-			this.xref[i][objGen] = objPos
+			this.setXrefEntry(i, objGen, objPos)
 		}
 	}
 
@@ -1159,11 +1064,34 @@ func (this *PdfReader) readXref() error {
 		return errors.Wrap(err, "Failed to read value for token: "+t)
 	}
 
-	// If /Root is set, then set trailer object so that /Root can be read later
-	if _, ok := trailer.Dictionary["/Root"]; ok {
+	// If /Root is set, then set trailer object so that /Root can be read later.
+	// Sections are read newest first, so the first trailer found wins.
+	if _, ok := trailer.Dictionary["/Root"]; ok && this.trailer == nil {
 		this.trailer = trailer
 	}
 
+	// In a hybrid-reference file, the xref stream holds entries (typically compressed objects) that the
+	// table lists as free.  Its entries take precedence over free entries of this section and over /Prev.
+	if stm, ok := trailer.Dictionary["/XRefStm"]; ok && stm.Int > 0 && !this.xrefVisited[stm.Int] {
+		this.xrefVisited[stm.Int] = true
+
+		r = this.readerAt(int64(stm.Int))
+
+		t, err = this.readToken(r)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read token")
+		}
+
+		_, err = this.readXrefStream(r, t, int64(stm.Int))
+		if err != nil {
+			return errors.Wrap(err, "Failed to read /XRefStm")
+		}
+	}
+
+	for _, id := range free {
+		this.setXrefFree(id)
+	}
+
 	// If a /Prev xref trailer is specified, parse that
 	if tr, ok := trailer.Dictionary["/Prev"]; ok {
 		// Resolve parent xref table
@@ -1174,142 +1102,291 @@ func (this *PdfReader) readXref() error {
 	return nil
 }
 
-// Read root (catalog object)
-func (this *PdfReader) readRoot() error {
-	var err error
+// Read a cross-reference stream (PDF 1.5) at offset starting with token t, and add its entries to the xref table.
+// Returns the stream dictionary.
+func (this *PdfReader) readXrefStream(r *tokenReader, t string, offset int64) (*PdfValue, error) {
+	v, err := this.readValue(r, t)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read XRef stream")
+	}
 
-	rootObjSpec := this.trailer.Dictionary["/Root"]
+	if v.Type != PDF_TYPE_OBJDEC {
+		return nil, errors.New("Expected xref to start with 'xref'.  Got: " + t)
+	}
 
-	// Read root (catalog)
-	this.catalog, err = this.resolveObject(rootObjSpec)
+	// Read next token
+	t, err = this.readToken(r)
 	if err != nil {
-		return errors.Wrap(err, "Failed to resolve root object")
+		return nil, errors.Wrap(err, "Failed to read token")
 	}
 
-	return nil
-}
+	// Read actual object value
+	v, err = this.readValue(r, t)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read value for token: "+t)
+	}
+
+	// Check to see if /Type is XRef
+	if v.Type != PDF_TYPE_DICTIONARY {
+		return nil, errors.New("Expected xref stream dictionary")
+	}
+	if _, ok := v.Dictionary["/Type"]; !ok || v.Dictionary["/Type"].Token != "/XRef" {
+		return nil, errors.New("Expected object type to be /XRef")
+	}
+
+	// Set root object
+	if _, ok := v.Dictionary["/Root"]; ok && this.trailer == nil {
+		// Just set the whole dictionary with /Root key to keep compatibiltiy with existing code
+		this.trailer = v
+	}
+
+	err = this.skipWhitespace(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to skip whitespace")
+	}
+
+	// Get stream length dictionary
+	lengthDict, ok := v.Dictionary["/Length"]
+	if !ok {
+		return nil, errors.New("Xref stream is missing /Length")
+	}
+
+	// Get number of bytes of stream
+	length := lengthDict.Int
+
+	// If lengthDict is an object reference, resolve the object and set length
+	if lengthDict.Type == PDF_TYPE_OBJREF {
+		lengthDict, err = this.resolveObject(lengthDict)
 
-// Read kids (pages inside a page tree)
-func (this *PdfReader) readKids(kids *PdfValue, r int) error {
-	// Loop through pages and add to result
-	for i := 0; i < len(kids.Array); i++ {
-		page, err := this.resolveObject(kids.Array[i])
 		if err != nil {
-			return errors.Wrap(err, "Failed to resolve page/pages object")
+			return nil, errors.Wrap(err, "Failed to resolve length object of stream")
 		}
 
-		objType := page.Value.Dictionary["/Type"].Token
-		if objType == "/Page" {
-			// Set page and increment curPage
-			this.pages[this.curPage] = page
-			this.curPage++
-		} else if objType == "/Pages" {
-			// Resolve kids
-			subKids, err := this.resolveObject(page.Value.Dictionary["/Kids"])
-			if err != nil {
-				return errors.Wrap(err, "Failed to resolve kids")
-			}
-
-			// Recurse into page tree
-			err = this.readKids(subKids, r+1)
-			if err != nil {
-				return errors.Wrap(err, "Failed to read kids")
-			}
-		} else {
-			return errors.Wrap(err, fmt.Sprintf("Unknown object type '%s'.  Expected: /Pages or /Page", objType))
+		if lengthDict.Value == nil {
+			return nil, errors.New("Invalid length object of stream")
 		}
-	}
 
-	return nil
-}
+		// Set length to resolved object value
+		length = lengthDict.Value.Int
+	}
 
-// Read all pages in PDF
-func (this *PdfReader) readPages() error {
-	var err error
+	err = this.checkStreamLength(length, offset)
+	if err != nil {
+		return nil, err
+	}
 
-	// resolve_pages_dict
-	pagesDict, err := this.resolveObject(this.catalog.Value.Dictionary["/Pages"])
+	t, err = this.readToken(r)
 	if err != nil {
-		return errors.Wrap(err, "Failed to resolve pages object")
+		return nil, errors.Wrap(err, "Failed to read token")
+	}
+	if t != "stream" {
+		return nil, errors.New("Expected next token to be: stream, got: " + t)
 	}
 
-	// This will normally return itself
-	kids, err := this.resolveObject(pagesDict.Value.Dictionary["/Kids"])
+	err = this.skipStreamEOL(r)
 	if err != nil {
-		return errors.Wrap(err, "Failed to resolve kids object")
+		return nil, errors.Wrap(err, "Failed to skip end of line")
 	}
 
-	// Get number of pages
-	pageCount, err := this.resolveObject(pagesDict.Value.Dictionary["/Count"])
+	// Read length bytes
+	data := make([]byte, length)
+
+	// Cannot use reader.Read() because that may not read all the bytes
+	_, err = io.ReadFull(r, data)
 	if err != nil {
-		return errors.Wrap(err, "Failed to get page count")
+		return nil, errors.Wrap(err, "Failed to read bytes from buffer")
 	}
-	this.pageCount = pageCount.Int
 
-	// Allocate pages
-	this.pages = make([]*PdfValue, pageCount.Int)
+	// Look for endstream token
+	t, err = this.readToken(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read token")
+	}
+	if t != "endstream" {
+		return nil, errors.New("Expected next token to be: endstream, got: " + t)
+	}
 
-	// Read kids
-	err = this.readKids(kids, 0)
+	// Now decode the stream data (applying /Filter and any /DecodeParms predictor)
+	p, err := this.decodeStream(&PdfValue{Type: PDF_TYPE_STREAM, Value: v, Stream: &PdfValue{Type: PDF_TYPE_STREAM, Bytes: data}})
 	if err != nil {
-		return errors.Wrap(err, "Failed to read kids")
+		return nil, errors.Wrap(err, "Failed to decode xref stream")
 	}
 
-	return nil
+	if _, ok := v.Dictionary["/W"]; !ok || len(v.Dictionary["/W"].Array) < 3 {
+		return nil, errors.New("Xref stream is missing /W")
+	}
+
+	firstFieldSize := v.Dictionary["/W"].Array[0].Int
+	middleFieldSize := v.Dictionary["/W"].Array[1].Int
+	lastFieldSize := v.Dictionary["/W"].Array[2].Int
+
+	fieldSize := firstFieldSize + middleFieldSize + lastFieldSize
+	if firstFieldSize < 0 || middleFieldSize < 0 || lastFieldSize < 0 || fieldSize <= 0 {
+		return nil, errors.New("Invalid xref stream field sizes")
+	}
+
+	// /Index holds pairs of first object id and number of entries; it defaults to [0 /Size]
+	var index []int
+	if idx, ok := v.Dictionary["/Index"]; ok {
+		if len(idx.Array) < 2 || len(idx.Array)%2 != 0 {
+			return nil, errors.New("Index array does not contain pairs of integers")
+		}
+
+		for _, n := range idx.Array {
+			index = append(index, n.Int)
+		}
+	} else {
+		size := len(p) / fieldSize
+		if s, ok := v.Dictionary["/Size"]; ok {
+			size = s.Int
+		}
+		index = []int{0, size}
+	}
+
+	pos := 0
+
+	for sub := 0; sub < len(index); sub += 2 {
+		for i := index[sub]; i < index[sub]+index[sub+1]; i++ {
+			if pos+fieldSize > len(p) {
+				log.Debugf("xref stream data ends before object %d", i)
+				return v, nil
+			}
+
+			row := p[pos : pos+fieldSize]
+			pos += fieldSize
+
+			// The type field defaults to 1 if its size is 0
+			objType := 1
+			if firstFieldSize > 0 {
+				objType = readXrefField(row[:firstFieldSize])
+			}
+			field2 := readXrefField(row[firstFieldSize : firstFieldSize+middleFieldSize])
+			field3 := readXrefField(row[firstFieldSize+middleFieldSize:])
+
+			switch objType {
+			case 0:
+				// Free object
+				this.setXrefFree(i)
+			case 1:
+				// Regular objects: field 2 is the position, field 3 the generation
+				this.setXrefEntry(i, field3, field2)
+			case 2:
+				// Compressed objects: object id (i) is located in StmObj (field 2) at index (field 3)
+				if !this.xrefClaimed(i) {
+					this.xrefStream[i] = [2]int{field2, field3}
+				}
+			}
+		}
+	}
+
+	return v, nil
 }
 
-// Get references to page resources for a given page number
-func (this *PdfReader) getPageResources(pageno int) (*PdfValue, error) {
+// Check whether an object id already has an entry from a newer xref section
+func (this *PdfReader) xrefClaimed(id int) bool {
+	if _, ok := this.xref[id]; ok {
+		return true
+	}
+	if _, ok := this.xrefStream[id]; ok {
+		return true
+	}
+	return this.xrefFree[id]
+}
+
+// Set the position of an object, unless a newer xref section already defined it
+func (this *PdfReader) setXrefEntry(id, gen, pos int) {
+	if this.xrefClaimed(id) {
+		return
+	}
+
+	this.xref[id] = map[int]int{gen: pos}
+}
+
+// Mark an object as free, so that definitions in older xref sections are ignored
+func (this *PdfReader) setXrefFree(id int) {
+	if this.xrefClaimed(id) {
+		return
+	}
+
+	this.xrefFree[id] = true
+}
+
+// Read root (catalog object)
+func (this *PdfReader) readRoot() error {
 	var err error
 
-	// Check to make sure page exists in pages slice
-	if len(this.pages) < pageno {
-		return nil, errors.New(fmt.Sprintf("Page %d does not exist!!", pageno))
+	rootObjSpec, ok := this.trailer.Dictionary["/Root"]
+	if !ok {
+		return errors.New("Trailer does not contain /Root")
 	}
 
-	// Resolve page object
-	page, err := this.resolveObject(this.pages[pageno-1])
+	// Read root (catalog)
+	this.catalog, err = this.resolveObject(rootObjSpec)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to resolve page object")
+		return errors.Wrap(err, "Failed to resolve root object")
 	}
+	if this.catalog.Value == nil || this.catalog.Value.Type != PDF_TYPE_DICTIONARY {
+		return errors.New("Root object is not a dictionary")
+	}
+
+	return nil
+}
 
-	// Check to see if /Resources exists in Dictionary
-	if _, ok := page.Value.Dictionary["/Resources"]; ok {
-		// Resolve /Resources object
-		res, err := this.resolveObject(page.Value.Dictionary["/Resources"])
+// Read the page tree root and the number of pages.  Page objects are resolved on demand by getPage, unless
+// the /Count of the root does not match its kids, in which case the whole tree is walked right away.
+func (this *PdfReader) readPages() error {
+	pagesSpec, ok := this.catalog.Value.Dictionary["/Pages"]
+	if !ok {
+		return errors.New("Catalog does not contain /Pages")
+	}
+
+	// resolve_pages_dict
+	pagesDict, err := this.resolveObject(pagesSpec)
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve pages object")
+	}
+	if pagesDict.Value == nil || pagesDict.Value.Type != PDF_TYPE_DICTIONARY {
+		return errors.New("Pages object is not a dictionary")
+	}
+	this.pagesRoot = pagesDict
+
+	if pageTreeNodeType(pagesDict) == "/Pages" {
+		_, _, ok, err := this.countPageTreeKids(pagesDict)
 		if err != nil {
-			return nil, errors.Wrap(err, "Failed to resolve resources object")
+			return errors.Wrap(err, "Failed to get page count")
 		}
 
-		// If type is PDF_TYPE_OBJECT, return its Value
-		if res.Type == PDF_TYPE_OBJECT {
-			return res.Value, nil
+		if ok {
+			// Allocate pages
+			this.pageCount = this.pageTreeCount(pagesDict)
+			this.pages = make([]*pageNode, this.pageCount)
+			return nil
 		}
+	}
 
-		// Otherwise, returned the resolved object
-		return res, nil
-	} else {
-		// If /Resources does not exist, check to see if /Parent exists and return that
-		if _, ok := page.Value.Dictionary["/Parent"]; ok {
-			// Resolve parent object
-			res, err := this.resolveObject(page.Value.Dictionary["/Parent"])
-			if err != nil {
-				return nil, errors.Wrap(err, "Failed to resolve parent object")
-			}
+	return this.loadAllPages()
+}
 
-			// If /Parent object type is PDF_TYPE_OBJECT, return its Value
-			if res.Type == PDF_TYPE_OBJECT {
-				return res.Value, nil
-			}
+// Get the resources of a page, which may be inherited from its ancestors in the page tree
+func (this *PdfReader) getPageResources(pageno int) (*PdfValue, error) {
+	page, err := this.getPageNode(pageno)
+	if err != nil {
+		return nil, err
+	}
 
-			// Otherwise, return the resolved parent object
-			return res, nil
-		}
+	resources, ok := page.attribute("/Resources")
+	if !ok {
+		// The page does not use any resources
+		return &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: make(map[string]*PdfValue, 0)}, nil
+	}
+
+	// Resolve /Resources object
+	res, err := this.resolveDirect(resources)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve resources object")
 	}
 
-	// Return an empty PdfValue if we got here
-	// TODO:  Improve error handling
-	return &PdfValue{}, nil
+	return res, nil
 }
 
 // Get page content and return a slice of PdfValue objects
@@ -1349,12 +1426,15 @@ func (this *PdfReader) getContent(pageno int) (string, error) {
 	var contents []*PdfValue
 
 	// Check to make sure page exists in pages slice
-	if len(this.pages) < pageno {
-		return "", errors.New(fmt.Sprintf("Page %d does not exist.", pageno))
+	if err := this.checkPage(pageno); err != nil {
+		return "", err
 	}
 
 	// Get page
-	page := this.pages[pageno-1]
+	page, err := this.getPage(pageno)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to resolve page object")
+	}
 
 	// FIXME: This could be slow, converting []byte to string and appending many times
 	buffer := ""
@@ -1387,73 +1467,32 @@ func (this *PdfReader) getContent(pageno int) (string, error) {
 // This will decode content if one or more /Filter (such as FlateDecode) is specified.
 // If there are multiple filters, they will be decoded in the order in which they were specified.
 func (this *PdfReader) rebuildContentStream(content *PdfValue) ([]byte, error) {
-	var err error
-	var tmpFilter *PdfValue
-
-	// Allocate slice of PdfValue
-	filters := make([]*PdfValue, 0)
-
-	// If content has a /Filter, append it to filters slice
-	if _, ok := content.Value.Dictionary["/Filter"]; ok {
-		filter := content.Value.Dictionary["/Filter"]
-
-		// If filter type is a reference, resolve it
-		if filter.Type == PDF_TYPE_OBJREF {
-			tmpFilter, err = this.resolveObject(filter)
-			if err != nil {
-				return nil, errors.Wrap(err, "Failed to resolve object")
-			}
-			filter = tmpFilter.Value
-		}
-
-		if filter.Type == PDF_TYPE_TOKEN {
-			// If filter type is a token (e.g. FlateDecode), appent it to filters slice
-			filters = append(filters, filter)
-		} else if filter.Type == PDF_TYPE_ARRAY {
-			// If filter type is an array, then there are multiple filters.  Set filters variable to array value.
-			filters = filter.Array
-		}
-
-	}
-
-	// Set stream variable to content bytes
-	stream := content.Stream.Bytes
-
-	// Loop through filters and apply each filter to stream
-	for i := 0; i < len(filters); i++ {
-		switch filters[i].Token {
-		case "/FlateDecode":
-			// Uncompress zlib compressed data
-			var out bytes.Buffer
-			zlibReader, _ := zlib.NewReader(bytes.NewBuffer(stream))
-			defer zlibReader.Close()
-			io.Copy(&out, zlibReader)
-
-			// Set stream to uncompressed data
-			stream = out.Bytes()
-		default:
-			return nil, errors.New("Unspported filter: " + filters[i].Token)
-		}
+	stream, err := this.decodeStream(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode content stream")
 	}
 
 	return stream, nil
 }
 
 func (this *PdfReader) getNumPages() (int, error) {
-	if this.pageCount == 0 {
+	pageCount := this.numPages()
+	if pageCount == 0 {
 		return 0, errors.New("Page count is 0")
 	}
 
-	return this.pageCount, nil
+	return pageCount, nil
 }
 
 func (this *PdfReader) getAllPageBoxes(k float64) (map[int]map[string]map[string]float64, error) {
 	var err error
 
+	pageCount := this.numPages()
+
 	// Allocate result with the number of available boxes
-	result := make(map[int]map[string]map[string]float64, len(this.pages))
+	result := make(map[int]map[string]map[string]float64, pageCount)
 
-	for i := 1; i <= len(this.pages); i++ {
+	for i := 1; i <= pageCount; i++ {
 		result[i], err = this.getPageBoxes(i, k)
 		if result[i] == nil {
 			return nil, errors.Wrap(err, "Unable to get page box")
@@ -1463,172 +1502,152 @@ func (this *PdfReader) getAllPageBoxes(k float64) (map[int]map[string]map[string
 	return result, nil
 }
 
-// Get all page box data
+// Get all effective page box data.  x, y, w and h are in points scaled by k, llx, lly, urx and ury in the user
+// space units of the page.
 func (this *PdfReader) getPageBoxes(pageno int, k float64) (map[string]map[string]float64, error) {
-	var err error
-
 	// Allocate result with the number of available boxes
 	result := make(map[string]map[string]float64, len(this.availableBoxes))
 
-	// Check to make sure page exists in pages slice
-	if len(this.pages) < pageno {
-		return nil, errors.New(fmt.Sprintf("Page %d does not exist?", pageno))
-	}
-
-	// Resolve page object
-	page, err := this.resolveObject(this.pages[pageno-1])
+	pageBoxes, err := this.PageBoxes(pageno)
 	if err != nil {
-		return nil, errors.New("Failed to resolve page object")
+		return nil, errors.Wrap(err, "Failed to get page boxes")
 	}
 
 	// Loop through available boxes and add to result
 	for i := 0; i < len(this.availableBoxes); i++ {
-		box, err := this.getPageBox(page, this.availableBoxes[i], k)
-		if err != nil {
-			return nil, errors.New("Failed to get page box")
-		}
-
-		result[this.availableBoxes[i]] = box
-	}
-
-	return result, nil
-}
+		box, _ := pageBoxes.Box(this.availableBoxes[i])
+		u := pageBoxes.UserUnit
 
-// Get a specific page box value (e.g. MediaBox) and return its values
-func (this *PdfReader) getPageBox(page *PdfValue, box_index string, k float64) (map[string]float64, error) {
-	var err error
-	var tmpBox *PdfValue
-
-	// Allocate 8 fields in result
-	result := make(map[string]float64, 8)
-
-	// Check to make sure box_index (e.g. MediaBox) exists in page dictionary
-	if _, ok := page.Value.Dictionary[box_index]; ok {
-		box := page.Value.Dictionary[box_index]
-
-		// If the box type is a reference, resolve it
-		if box.Type == PDF_TYPE_OBJREF {
-			tmpBox, err = this.resolveObject(box)
-			if err != nil {
-				return nil, errors.New("Failed to resolve object")
-			}
-			box = tmpBox.Value
-		}
-
-		if box.Type == PDF_TYPE_ARRAY {
-			// If the box type is an array, calculate scaled value based on k
-			result["x"] = box.Array[0].Real / k
-			result["y"] = box.Array[1].Real / k
-			result["w"] = math.Abs(box.Array[0].Real-box.Array[2].Real) / k
-			result["h"] = math.Abs(box.Array[1].Real-box.Array[3].Real) / k
-			result["llx"] = math.Min(box.Array[0].Real, box.Array[2].Real)
-			result["lly"] = math.Min(box.Array[1].Real, box.Array[3].Real)
-			result["urx"] = math.Max(box.Array[0].Real, box.Array[2].Real)
-			result["ury"] = math.Max(box.Array[1].Real, box.Array[3].Real)
-		} else {
-			// TODO: Improve error handling
-			return nil, errors.New("Could not get page box")
+		result[this.availableBoxes[i]] = map[string]float64{
+			"x":   box.Llx * u / k,
+			"y":   box.Lly * u / k,
+			"w":   box.Width() * u / k,
+			"h":   box.Height() * u / k,
+			"llx": box.Llx,
+			"lly": box.Lly,
+			"urx": box.Urx,
+			"ury": box.Ury,
 		}
-	} else if _, ok := page.Value.Dictionary["/Parent"]; ok {
-		parentObj, err := this.resolveObject(page.Value.Dictionary["/Parent"])
-		if err != nil {
-			return nil, errors.Wrap(err, "Could not resolve parent object")
-		}
-
-		// If the page box is inherited from /Parent, recursively return page box of parent
-		return this.getPageBox(parentObj, box_index, k)
 	}
 
 	return result, nil
 }
 
-// Get page rotation for a page number
+// Get page rotation for a page number, which may be inherited from its ancestors in the page tree
 func (this *PdfReader) getPageRotation(pageno int) (*PdfValue, error) {
-	// Check to make sure page exists in pages slice
-	if len(this.pages) < pageno {
-		return nil, errors.New(fmt.Sprintf("Page %d does not exist!!!!", pageno))
+	page, err := this.getPageNode(pageno)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve page object")
 	}
 
-	return this._getPageRotation(this.pages[pageno-1])
-}
-
-// Get page rotation for a page object spec
-func (this *PdfReader) _getPageRotation(page *PdfValue) (*PdfValue, error) {
-	var err error
+	rotate, ok := page.attribute("/Rotate")
+	if !ok {
+		return &PdfValue{Int: 0}, nil
+	}
 
-	// Resolve page object
-	page, err = this.resolveObject(page)
+	res, err := this.resolveDirect(rotate)
 	if err != nil {
-		return nil, errors.New("Failed to resolve page object")
+		return nil, errors.Wrap(err, "Failed to resolve rotate object")
 	}
 
-	// Check to make sure /Rotate exists in page dictionary
-	if _, ok := page.Value.Dictionary["/Rotate"]; ok {
-		res, err := this.resolveObject(page.Value.Dictionary["/Rotate"])
-		if err != nil {
-			return nil, errors.New("Failed to resolve rotate object")
-		}
-
-		// If the type is PDF_TYPE_OBJECT, return its value
-		if res.Type == PDF_TYPE_OBJECT {
-			return res.Value, nil
-		}
+	return res, nil
+}
 
-		// Otherwise, return the object
-		return res, nil
-	} else {
-		// Check to see if parent has a rotation
-		if _, ok := page.Value.Dictionary["/Parent"]; ok {
-			// Recursively return /Parent page rotation
-			res, err := this._getPageRotation(page.Value.Dictionary["/Parent"])
-			if err != nil {
-				return nil, errors.Wrap(err, "Failed to get page rotation for parent")
+func (this *PdfReader) read() error {
+	// Only run once
+	if !this.alreadyRead {
+		err := this.readDocument(false)
+		if err != nil {
+			// Rebuilding the xref table does not help with a wrong password or an unsupported encryption
+			if !this.repairXref || errors.Is(err, ErrEncrypted) || errors.Is(err, ErrInvalidPassword) || errors.Is(err, ErrUnsupportedEncryption) {
+				return err
 			}
 
-			// If the type is PDF_TYPE_OBJECT, return its value
-			if res.Type == PDF_TYPE_OBJECT {
-				return res.Value, nil
+			// Rebuild the cross-reference table by scanning the file and try again
+			log.Debugf("failed to read pdf, rebuilding xref table: %v", err)
+			err = this.readDocument(true)
+			if err != nil {
+				return errors.Wrap(err, "Failed to read pdf with rebuilt xref table")
 			}
-
-			// Otherwise, return the object
-			return res, nil
 		}
+
+		// Now that this has been read, do not read again
+		this.alreadyRead = true
 	}
 
-	return &PdfValue{Int: 0}, nil
+	return nil
 }
 
-func (this *PdfReader) read() error {
-	// Only run once
-	if !this.alreadyRead {
-		var err error
+// Read the xref table, trailer, catalog and pages.
+// If rebuild is true, the xref table is reconstructed by scanning the whole file instead of using startxref.
+func (this *PdfReader) readDocument(rebuild bool) error {
+	var err error
 
+	// Reset any state left over from a previous attempt
+	this.xref = make(map[int]map[int]int, 0)
+	this.xrefStream = make(map[int][2]int, 0)
+	this.xrefVisited = make(map[int]bool, 0)
+	this.xrefFree = make(map[int]bool, 0)
+	this.trailer = nil
+	this.crypt = nil
+	this.cache = nil
+	this.pagesWalked = false
+	this.pageNumbers = nil
+	this.pageObjects = nil
+
+	if rebuild {
+		// There is no usable xref section to refer to from an incremental update
+		this.startXref = -1
+
+		err = this.rebuildXref()
+		if err != nil {
+			return &XrefError{Offset: -1, Err: errors.Wrap(err, "Failed to rebuild xref table")}
+		}
+	} else {
 		// Find xref position
 		err = this.findXref()
 		if err != nil {
-			return errors.Wrap(err, "Failed to find xref position")
+			return &XrefError{Offset: -1, Err: errors.Wrap(err, "Failed to find xref position")}
 		}
+		this.startXref = this.xrefPos
 
 		// Parse xref table
 		err = this.readXref()
 		if err != nil {
-			return errors.Wrap(err, "Failed to read xref table")
+			return &XrefError{Offset: int64(this.xrefPos), Err: errors.Wrap(err, "Failed to read xref table")}
 		}
+	}
 
-		// Read catalog
-		err = this.readRoot()
-		if err != nil {
-			return errors.Wrap(err, "Failed to read root")
-		}
+	if this.trailer == nil {
+		return &XrefError{Offset: int64(this.xrefPos), Err: errors.New("Could not find trailer with /Root")}
+	}
 
-		// Read pages
-		err = this.readPages()
-		if err != nil {
-			return errors.Wrap(err, "Failed to to read pages")
-		}
+	// Set up decryption if the document is encrypted
+	err = this.readEncryption()
+	if err != nil {
+		return errors.Wrap(err, "Failed to read encryption dictionary")
+	}
 
-		// Now that this has been read, do not read again
-		this.alreadyRead = true
+	// Only cache objects once they can be decrypted
+	if this.cacheSize > 0 {
+		this.cache = newObjectCache(this.cacheSize)
+	}
+
+	if rebuild {
+		this.indexRepairedObjectStreams()
+		this.findRepairedCatalog()
+	}
+
+	// Read catalog
+	err = this.readRoot()
+	if err != nil {
+		return errors.Wrap(err, "Failed to read root")
+	}
+
+	// Read pages
+	err = this.readPages()
+	if err != nil {
+		return errors.Wrap(err, "Failed to to read pages")
 	}
 
 	return nil
@@ -1646,23 +1665,44 @@ func (this *PdfReader) getFontDefinitions(pageNumber int) (map[string]*text.Font
 	if !ok {
 		return nil, fmt.Errorf("failed to find font dictionary on page %d", pageNumber)
 	}
-	pageFonts, err := this.resolveObject(pageResourceFont)
-	for fontName := range pageFonts.Value.Dictionary {
+	pageFonts, err := this.resolveDirect(pageResourceFont)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve font dictionary")
+	}
+	for fontName, fontLib := range pageFonts.Dictionary {
 		if _, found := fontDefinitions[fontName]; !found {
 			newFont := text.FontDefinition{}
 			newFont.Name = fontName
-			fontLib := pageFonts.Value.Dictionary[fontName]
-			fontDef, err := this.resolveObject(fontLib)
+			fontDef, err := this.resolveDirect(fontLib)
 			if err != nil {
-				return nil, err
+				return nil, &FontError{Font: fontName, Err: err}
+			}
+			if fontDef.Type != PDF_TYPE_DICTIONARY {
+				return nil, &FontError{Font: fontName, Err: errors.New("Font is not a dictionary")}
+			}
+			if v, ok := fontDef.Dictionary["/Type"]; ok {
+				newFont.Type = v.Int
+			}
+			if v, ok := fontDef.Dictionary["/FontDescriptor"]; ok {
+				newFont.Descriptor = v.Int
+			}
+			if v, ok := fontDef.Dictionary["/FirstChar"]; ok {
+				newFont.FirstChar = uint8(v.Int)
+			}
+			if v, ok := fontDef.Dictionary["/LastChar"]; ok {
+				newFont.LastChar = uint8(v.Int)
 			}
-			newFont.Type = fontDef.Value.Dictionary["/Type"].Int
-			newFont.Descriptor = fontDef.Value.Dictionary["/FontDescriptor"].Int
-			newFont.FirstChar = uint8(fontDef.Value.Dictionary["/FirstChar"].Int)
-			newFont.LastChar = uint8(fontDef.Value.Dictionary["/LastChar"].Int)
-			newFont.Base = fontDef.Value.Dictionary["/BaseFont"].String
-			for _, width := range fontDef.Value.Dictionary["/Widths"].Array {
-				newFont.Widths = append(newFont.Widths, width.Int)
+			if v, ok := fontDef.Dictionary["/BaseFont"]; ok {
+				newFont.Base = v.String
+			}
+			if v, ok := fontDef.Dictionary["/Widths"]; ok {
+				widths, err := this.resolveDirect(v)
+				if err != nil {
+					return nil, &FontError{Font: fontName, Err: err}
+				}
+				for _, width := range widths.Array {
+					newFont.Widths = append(newFont.Widths, width.Int)
+				}
 			}
 			fontDefinitions[fontName] = &newFont
 			log.Debugf("loaded font %s for page %d: base=%s  type=%d", newFont.Name, pageNumber, newFont.Base, newFont.Type)
@@ -1686,3 +1726,12 @@ func (this *PdfReader) getPageTextBlocks(pageNumber int) (blocks []string, block
 	log.Debugf("retrieved %d text blocks from page %d ", len(blocks), pageNumber)
 	return blocks, nil
 }
+
+// Read a big-endian integer field of an xref stream entry
+func readXrefField(b []byte) int {
+	result := 0
+	for i := 0; i < len(b); i++ {
+		result = result<<8 | int(b[i])
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package gofpdi
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestXrefFormats(t *testing.T) {
+	// A cross-reference table, a cross-reference stream, and a table with a stream listing the compressed objects
+	for _, file := range []string{"simple.pdf", "xref-stream.pdf", "hybrid.pdf"} {
+		reader := readTestFile(t, file)
+		checkPages(t, file, reader, 1, 2, 3)
+
+		for _, ref := range reader.ObjectRefs() {
+			if _, err := reader.Resolve(ref); err != nil {
+				t.Errorf("%s: object %d: %v", file, ref.Id, err)
+			}
+		}
+	}
+}
+
+func TestInvalidStreamLength(t *testing.T) {
+	// Lengths past the end of the file are rejected before the stream data is allocated
+	for _, length := range []string{"99999999999", "-1", "1000"} {
+		data := buildTestPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R >>",
+			"<< /Length "+length+" >>\nstream\nq Q\nendstream",
+		)
+
+		reader, err := NewPdfReaderFromStream(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := reader.Object(4, 0); err == nil {
+			t.Errorf("stream with /Length %s was read", length)
+		}
+	}
+
+	data, err := ioutil.ReadFile("testdata/xref-stream.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	damaged := bytes.Replace(data, []byte("/Length 76 >>"), []byte("/Length -1 >>"), 1)
+	if bytes.Equal(damaged, data) {
+		t.Fatal("xref stream /Length not found")
+	}
+	if _, err := NewPdfReaderFromStream(bytes.NewReader(damaged)); err == nil {
+		t.Error("xref stream with /Length -1 was read")
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	// A small cache makes the goroutines evict each other's objects and object streams
+	reader := readTestFile(t, "xref-stream.pdf", WithObjectCacheSize(2000))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			exporter, err := NewExporterFromReader(reader)
+			if err != nil {
+				errs <- err
+				return
+			}
+			for n := 0; n < 20; n++ {
+				for pageno := 1; pageno <= 3; pageno++ {
+					text, err := exporter.GetPagePlainText(pageno)
+					if err != nil {
+						errs <- err
+						return
+					}
+					if want := fmt.Sprintf("Hello page %d\n", pageno); text != want {
+						errs <- fmt.Errorf("page %d text %q, want %q", pageno, text, want)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if stats := reader.CacheStats(); stats.Evictions == 0 {
+		t.Errorf("no objects were evicted: %+v", stats)
+	}
+}
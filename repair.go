@@ -0,0 +1,250 @@
+package gofpdi
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Size of the chunks read while scanning a damaged file
+	repairChunkSize = 1 << 20
+
+	// Number of bytes each chunk overlaps the previous one, so that matches spanning two chunks are found
+	repairChunkOverlap = 64
+)
+
+var (
+	repairObjRegex     = regexp.MustCompile(`(?:^|[^0-9])([0-9]{1,10})[\x00\t\n\f\r ]+([0-9]{1,5})[\x00\t\n\f\r ]+obj\b`)
+	repairTrailerRegex = regexp.MustCompile(`trailer\b`)
+	repairObjStmRegex  = regexp.MustCompile(`/Type[\x00\t\n\f\r ]*/ObjStm\b`)
+	repairXRefRegex    = regexp.MustCompile(`/Type[\x00\t\n\f\r ]*/XRef\b`)
+)
+
+// An object header found while scanning a damaged file
+type repairObject struct {
+	id     int
+	gen    int
+	offset int
+}
+
+// Reconstruct the xref table by scanning the whole file for "N G obj" headers, trailer dictionaries and
+// cross-reference streams.  Object offsets found this way are absolute, so junk before %PDF is tolerated.
+func (this *PdfReader) rebuildXref() error {
+	var objects []repairObject
+	var trailers, objStms, xrefStms []int
+
+	buf := make([]byte, repairChunkSize+2*repairChunkOverlap)
+
+	for base := int64(0); base < this.nBytes; base += repairChunkSize {
+		// Start reading a little before the chunk so that the character preceding an object number is known
+		start := base - repairChunkOverlap
+		if start < 0 {
+			start = 0
+		}
+
+		n, err := this.f.ReadAt(buf[:base-start+repairChunkSize+repairChunkOverlap], start)
+		if err != nil && err != io.EOF {
+			return errors.Wrap(err, "Failed to read file")
+		}
+		chunk := buf[:n]
+
+		// Only accept matches that start within this chunk; the overlap is handled by the next chunk
+		inChunk := func(pos int) bool {
+			abs := start + int64(pos)
+			return abs >= base && abs < base+repairChunkSize
+		}
+
+		for _, m := range repairObjRegex.FindAllSubmatchIndex(chunk, -1) {
+			if !inChunk(m[2]) {
+				continue
+			}
+			id, _ := strconv.Atoi(string(chunk[m[2]:m[3]]))
+			gen, _ := strconv.Atoi(string(chunk[m[4]:m[5]]))
+			objects = append(objects, repairObject{id: id, gen: gen, offset: int(start) + m[2]})
+		}
+
+		for _, marker := range []struct {
+			regex  *regexp.Regexp
+			result *[]int
+		}{
+			{repairTrailerRegex, &trailers},
+			{repairObjStmRegex, &objStms},
+			{repairXRefRegex, &xrefStms},
+		} {
+			for _, m := range marker.regex.FindAllIndex(chunk, -1) {
+				if inChunk(m[0]) {
+					*marker.result = append(*marker.result, int(start)+m[0])
+				}
+			}
+		}
+	}
+
+	if len(objects) == 0 {
+		return errors.New("No objects found while rebuilding xref table")
+	}
+
+	// Objects later in the file replace earlier definitions (incremental updates)
+	for _, obj := range objects {
+		this.xref[obj.id] = map[int]int{obj.gen: obj.offset}
+	}
+
+	log.Debugf("rebuilt xref table with %d objects", len(this.xref))
+
+	// Merge all trailer dictionaries, later ones taking precedence
+	trailer := &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: make(map[string]*PdfValue, 0)}
+
+	for _, pos := range trailers {
+		dict, err := this.readDictionaryAt(int64(pos) + int64(len("trailer")))
+		if err != nil {
+			log.Debugf("skipping unreadable trailer at offset %d: %v", pos, err)
+			continue
+		}
+		for k, v := range dict.Dictionary {
+			trailer.Dictionary[k] = v
+		}
+	}
+
+	// Cross-reference stream dictionaries serve as trailers too
+	for _, obj := range this.repairObjectsAt(objects, xrefStms) {
+		res, err := this.resolveObject(&PdfValue{Type: PDF_TYPE_OBJREF, Id: obj.id, Gen: obj.gen})
+		if err != nil || res.Value.Type != PDF_TYPE_DICTIONARY {
+			continue
+		}
+		for _, k := range []string{"/Root", "/Info", "/ID", "/Encrypt"} {
+			if v, ok := res.Value.Dictionary[k]; ok {
+				trailer.Dictionary[k] = v
+			}
+		}
+	}
+
+	this.trailer = trailer
+
+	// Object streams are indexed once decryption has been set up
+	this.repairObjStms = this.repairObjectsAt(objects, objStms)
+
+	return nil
+}
+
+// Find the objects containing the given offsets, i.e. the last object header before each offset
+func (this *PdfReader) repairObjectsAt(objects []repairObject, offsets []int) []repairObject {
+	result := make([]repairObject, 0)
+
+	for _, offset := range offsets {
+		i := sort.Search(len(objects), func(i int) bool {
+			return objects[i].offset >= offset
+		})
+		if i > 0 {
+			result = append(result, objects[i-1])
+		}
+	}
+
+	return result
+}
+
+// Register the objects stored in object streams found while rebuilding the xref table
+func (this *PdfReader) indexRepairedObjectStreams() {
+	for _, stm := range this.repairObjStms {
+		// Skip object streams that were replaced by a later definition of the same object
+		if this.xref[stm.id][stm.gen] != stm.offset {
+			continue
+		}
+
+		objStm, err := this.getObjectStream(stm.id)
+		if err != nil {
+			log.Debugf("skipping unreadable object stream %d: %v", stm.id, err)
+			continue
+		}
+
+		for i, id := range objStm.ids {
+			// A direct object defined after the object stream takes precedence
+			if offsets, ok := this.xref[id]; ok {
+				newer := false
+				for _, offset := range offsets {
+					if offset > stm.offset {
+						newer = true
+					}
+				}
+				if newer {
+					continue
+				}
+				delete(this.xref, id)
+			}
+
+			this.xrefStream[id] = [2]int{stm.id, i}
+		}
+	}
+
+	this.repairObjStms = nil
+}
+
+// Read a dictionary at a given offset of the file
+func (this *PdfReader) readDictionaryAt(offset int64) (*PdfValue, error) {
+	r := this.readerAt(offset)
+
+	token, err := this.readToken(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read token")
+	}
+	if token != "<<" {
+		return nil, errors.New("Expected dictionary, got: " + token)
+	}
+
+	value, err := this.readValue(r, token)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read dictionary")
+	}
+
+	return value, nil
+}
+
+// If no trailer of a damaged file points to the catalog, use the last object of type /Catalog in the file, which
+// is the newest one after incremental updates.  Objects in object streams are at the offset of their stream.
+func (this *PdfReader) findRepairedCatalog() {
+	if _, ok := this.trailer.Dictionary["/Root"]; ok {
+		return
+	}
+
+	ids := make([]int, 0, len(this.xref)+len(this.xrefStream))
+	for id := range this.xref {
+		ids = append(ids, id)
+	}
+	for id := range this.xrefStream {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var root *PdfValue
+	rootOffset := -1
+	for _, id := range ids {
+		gen, offset := 0, -1
+		for g, pos := range this.xref[id] {
+			gen, offset = g, pos
+		}
+		if offset < 0 {
+			if entry, ok := this.xrefStream[id]; ok {
+				for _, pos := range this.xref[entry[0]] {
+					offset = pos
+				}
+			}
+		}
+
+		res, err := this.resolveObject(&PdfValue{Type: PDF_TYPE_OBJREF, Id: id, Gen: gen})
+		if err != nil || res.Value == nil {
+			continue
+		}
+
+		if t, ok := res.Value.Dictionary["/Type"]; ok && t.Token == "/Catalog" && (root == nil || offset > rootOffset) {
+			root = &PdfValue{Type: PDF_TYPE_OBJREF, Id: id, Gen: gen}
+			rootOffset = offset
+		}
+	}
+
+	if root != nil {
+		this.trailer.Dictionary["/Root"] = root
+	}
+}
@@ -0,0 +1,25 @@
+package gofpdi
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestXrefRepair(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/two-catalogs.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewPdfReaderFromStream(bytes.NewReader(data)); !errors.Is(err, ErrCorruptXref) {
+		t.Fatalf("got %v, want ErrCorruptXref", err)
+	}
+
+	// The file has no xref table and two catalogs; the one written last (with the lower id) is the current one
+	reader := readTestFile(t, "two-catalogs.pdf", WithXrefRepair())
+	if got := pageText(t, reader, 1); got != "New catalog\n" {
+		t.Errorf("page text %q, want %q", got, "New catalog\n")
+	}
+}
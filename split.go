@@ -0,0 +1,106 @@
+package gofpdi
+
+import (
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Split writes the pages of each range into a separate document.  newOutput is called with the index of each range
+// to get the writer of its document, which is closed after the document is written if it is an io.Closer.  Each
+// document only contains the objects used by its pages (see Document.CopyPages).
+func Split(reader *PdfReader, ranges []PageRange, newOutput func(index int) (io.Writer, error)) error {
+	for i, r := range ranges {
+		pagenos, err := pageRangeNumbers([]PageRange{r}, reader.numPages())
+		if err != nil {
+			return errors.Wrapf(err, "Invalid page range %d", i)
+		}
+
+		w, err := newOutput(i)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create output %d", i)
+		}
+
+		err = splitRange(reader, pagenos, w)
+		if closer, ok := w.(io.Closer); ok {
+			if closeErr := closer.Close(); err == nil && closeErr != nil {
+				err = errors.Wrapf(closeErr, "Failed to close output %d", i)
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Failed to write output %d", i)
+		}
+	}
+
+	return nil
+}
+
+// Write pages into a new document
+func splitRange(reader *PdfReader, pagenos []int, w io.Writer) error {
+	doc := NewDocument(w)
+
+	if err := doc.CopyPages(reader, pagenos...); err != nil {
+		return err
+	}
+
+	return doc.Close()
+}
+
+// SplitEvery returns the page ranges that split a document into parts of n pages.  The last part has fewer pages
+// if the page count is not a multiple of n.
+func SplitEvery(reader *PdfReader, n int) ([]PageRange, error) {
+	if n < 1 {
+		return nil, errors.New("Invalid number of pages per part")
+	}
+
+	numPages := reader.numPages()
+	ranges := make([]PageRange, 0, (numPages+n-1)/n)
+	for from := 1; from <= numPages; from += n {
+		to := from + n - 1
+		if to > numPages {
+			to = numPages
+		}
+		ranges = append(ranges, PageRange{From: from, To: to})
+	}
+
+	return ranges, nil
+}
+
+// SplitAtBookmarks returns the page ranges that split a document at the target page of each top level item of the
+// outline.  Pages before the first of them are a part of their own.  Documents without an outline are not split.
+func SplitAtBookmarks(reader *PdfReader) ([]PageRange, error) {
+	outlines, err := reader.Outlines()
+	if err != nil {
+		return nil, err
+	}
+
+	numPages := reader.numPages()
+
+	// First pages of the parts
+	starts := []int{1}
+	for _, item := range outlines {
+		if item.Page > 1 && item.Page <= numPages {
+			starts = append(starts, item.Page)
+		}
+	}
+	sort.Ints(starts)
+
+	ranges := make([]PageRange, 0, len(starts))
+	for i, from := range starts {
+		if i > 0 && from == starts[i-1] {
+			continue
+		}
+
+		to := numPages
+		for _, next := range starts[i+1:] {
+			if next > from {
+				to = next - 1
+				break
+			}
+		}
+		ranges = append(ranges, PageRange{From: from, To: to})
+	}
+
+	return ranges, nil
+}
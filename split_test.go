@@ -0,0 +1,42 @@
+package gofpdi
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	reader := readTestFile(t, "simple.pdf")
+
+	ranges, err := SplitEvery(reader, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []PageRange{{From: 1, To: 2}, {From: 3, To: 3}}; !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("SplitEvery = %v, want %v", ranges, want)
+	}
+
+	outputs := make([]*bytes.Buffer, len(ranges))
+	err = Split(reader, ranges, func(index int) (io.Writer, error) {
+		outputs[index] = &bytes.Buffer{}
+		return outputs[index], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := [][]int{{1, 2}, {3}}
+	for i, output := range outputs {
+		part, err := NewPdfReaderFromStream(bytes.NewReader(output.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to read part %d: %v", i, err)
+		}
+		checkPages(t, "part", part, pages[i]...)
+	}
+
+	if _, err := SplitEvery(reader, 0); err == nil {
+		t.Error("SplitEvery accepted 0 pages per part")
+	}
+}
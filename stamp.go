@@ -0,0 +1,628 @@
+package gofpdi
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	// Image formats supported by StampImage
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/pkg/errors"
+)
+
+// StampPosition is the point of the page a stamp is aligned with
+type StampPosition int
+
+const (
+	StampCenter StampPosition = iota
+	StampTopLeft
+	StampTop
+	StampTopRight
+	StampBottomLeft
+	StampBottom
+	StampBottomRight
+)
+
+// StampOptions configures how a stamp is placed on the pages.  Positions are relative to the crop box of each page
+// as it is displayed, so stamps are upright on rotated pages.  Sizes are in points (1/72 inch).
+type StampOptions struct {
+	Position StampPosition
+	Margin   float64 // distance from the edges of the page, unless the stamp is centered
+	X        float64 // offset to the right
+	Y        float64 // offset upwards
+	Rotation float64 // counterclockwise rotation around the center of the stamp in degrees, e.g. 45 for a watermark
+	Scale    float64 // scale of imported pages, 1 if 0
+
+	// Opacity from 0 to 1.  0, the default, stands for opaque.
+	Opacity float64
+
+	// Draw the stamp underneath the page content instead of on top of it
+	Underlay bool
+
+	// Pages to stamp, all pages if nil
+	Pages []PageRange
+}
+
+// TextStamp is a line of text drawn with a standard font
+type TextStamp struct {
+	Text     string
+	Font     string     // standard font, e.g. "Helvetica" (the default), "Helvetica-Bold", "Times-Roman" or "Courier"
+	FontSize float64    // 12 if 0
+	Color    [3]float64 // RGB, each component in the range 0.0 to 1.0
+
+	// Text of each page, e.g. page or Bates numbers.  Text is used if PageText is nil.
+	PageText func(pageno int) string
+}
+
+// BatesNumber returns a function for TextStamp.PageText that numbers pages with a prefix and a zero padded number,
+// starting at start on the first page, e.g. "ACME000001"
+func BatesNumber(prefix string, start, digits int) func(pageno int) string {
+	return func(pageno int) string {
+		return fmt.Sprintf("%s%0*d", prefix, digits, start+pageno-1)
+	}
+}
+
+// Stamper overlays text, images and pages of other documents on the pages of a document, or places them underneath
+// the page content, and saves them as an incremental update.  The original page objects, annotations and
+// structure are kept: each stamped page gets an additional content stream and additional resources.
+type Stamper struct {
+	reader *PdfReader
+	update *IncrementalUpdate
+
+	// Standard fonts, by name, and graphics states, by opacity, added to the update
+	fonts   map[string]*PdfValue
+	opacity map[float64]*PdfValue
+
+	// Content stream that saves the graphics state before the page content, so overlays start from the initial state
+	save *PdfValue
+
+	// Objects of other documents added to the update, by document and object id
+	imported map[*PdfReader]map[int]*PdfValue
+}
+
+// What a stamp draws on a page, and the resources it uses
+type stampContent struct {
+	width     float64
+	height    float64
+	content   string // drawing operators in the space of the stamp, with the origin at its lower left corner
+	resources map[string]map[string]*PdfValue
+}
+
+// NewStamper creates a stamper for the document read by reader.  Encrypted documents and documents whose xref
+// table had to be rebuilt cannot be stamped (see NewIncrementalUpdate).
+func NewStamper(reader *PdfReader) (*Stamper, error) {
+	update, err := NewIncrementalUpdate(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stamper{
+		reader:   reader,
+		update:   update,
+		fonts:    make(map[string]*PdfValue, 0),
+		opacity:  make(map[float64]*PdfValue, 0),
+		imported: make(map[*PdfReader]map[int]*PdfValue, 0),
+	}, nil
+}
+
+// StampText draws a line of text on the pages
+func (this *Stamper) StampText(text *TextStamp, opts *StampOptions) error {
+	base := "/" + strings.TrimPrefix(text.Font, "/")
+	if base == "/" {
+		base = "/Helvetica"
+	}
+
+	size := text.FontSize
+	if size <= 0 {
+		size = 12
+	}
+
+	font := standardFontMetrics(base)
+	fontRef := this.standardFontRef(base)
+
+	return this.stamp(opts, func(pageno int) (*stampContent, error) {
+		s := text.Text
+		if text.PageText != nil {
+			s = text.PageText(pageno)
+		}
+
+		encoded, _ := encodeWinAnsi(strings.NewReplacer("\r", " ", "\n", " ").Replace(s))
+
+		return &stampContent{
+			width:  font.width(encoded) * size / 1000,
+			height: size * (textAscent + textDescent),
+			content: fmt.Sprintf("%s %s %s rg\nBT\n/GOFPDIF %s Tf\n0 %s Td\n(%s) Tj\nET\n",
+				formatNumber(text.Color[0]), formatNumber(text.Color[1]), formatNumber(text.Color[2]), formatNumber(size),
+				formatNumber(size*textDescent), escapeLiteralString(encoded)),
+			resources: map[string]map[string]*PdfValue{"/Font": {"/GOFPDIF": fontRef}},
+		}, nil
+	})
+}
+
+// StampImage draws a JPEG, PNG or GIF image on the pages.  If width or height is 0, it is computed from the other so
+// the aspect ratio of the image is kept; if both are 0, the image is drawn at 72 pixels per inch.
+func (this *Stamper) StampImage(r io.Reader, width, height float64, opts *StampOptions) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read image")
+	}
+
+	ref, w, h, err := this.addImage(data)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case width == 0 && height == 0:
+		width, height = float64(w), float64(h)
+	case width == 0:
+		width = height * float64(w) / float64(h)
+	case height == 0:
+		height = width * float64(h) / float64(w)
+	}
+
+	content := &stampContent{
+		width:     width,
+		height:    height,
+		content:   fmt.Sprintf("%s 0 0 %s 0 0 cm\n/GOFPDISTAMPIMG Do\n", formatNumber(width), formatNumber(height)),
+		resources: map[string]map[string]*PdfValue{"/XObject": {"/GOFPDISTAMPIMG": ref}},
+	}
+
+	return this.stamp(opts, func(pageno int) (*stampContent, error) {
+		return content, nil
+	})
+}
+
+// StampPage draws a page of a document, e.g. a letterhead, on the pages.  The page may also be one of the stamped
+// document itself.
+func (this *Stamper) StampPage(source *PdfReader, pageno int, boxName string, opts *StampOptions) error {
+	if opts == nil {
+		opts = &StampOptions{}
+	}
+
+	writer, err := NewPdfWriter("")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create writer")
+	}
+
+	tplid, err := writer.ImportPage(source, pageno, boxName)
+	if err != nil {
+		return err
+	}
+	tpl := writer.tpls[tplid]
+
+	imported, ok := this.imported[source]
+	if !ok {
+		imported = make(map[int]*PdfValue, 0)
+		this.imported[source] = imported
+	}
+	resources, err := this.update.importValue(source, tpl.Resources, imported)
+	if err != nil {
+		return errors.Wrap(err, "Failed to import page resources")
+	}
+
+	bbox := make([]*PdfValue, 0, 4)
+	for _, key := range []string{"llx", "lly", "urx", "ury"} {
+		bbox = append(bbox, &PdfValue{Type: PDF_TYPE_REAL, Real: tpl.Box[key]})
+	}
+	matrix := make([]*PdfValue, 0, 6)
+	for _, v := range writer.templateMatrix(tpl) {
+		matrix = append(matrix, &PdfValue{Type: PDF_TYPE_REAL, Real: v})
+	}
+
+	ref, err := this.addStream([]byte(tpl.Buffer), map[string]*PdfValue{
+		"/Type":      {Type: PDF_TYPE_TOKEN, Token: "/XObject"},
+		"/Subtype":   {Type: PDF_TYPE_TOKEN, Token: "/Form"},
+		"/BBox":      {Type: PDF_TYPE_ARRAY, Array: bbox},
+		"/Matrix":    {Type: PDF_TYPE_ARRAY, Array: matrix},
+		"/Resources": resources,
+	})
+	if err != nil {
+		return err
+	}
+
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+
+	content := &stampContent{
+		width:     tpl.W * scale,
+		height:    tpl.H * scale,
+		content:   fmt.Sprintf("%s 0 0 %s 0 0 cm\n/GOFPDISTAMPPAGE Do\n", formatNumber(scale), formatNumber(scale)),
+		resources: map[string]map[string]*PdfValue{"/XObject": {"/GOFPDISTAMPPAGE": ref}},
+	}
+
+	return this.stamp(opts, func(pageno int) (*stampContent, error) {
+		return content, nil
+	})
+}
+
+// Write writes the original document followed by an incremental update holding the stamped pages
+func (this *Stamper) Write(w io.Writer) error {
+	return this.update.Write(w)
+}
+
+// WriteUpdate writes only the incremental update, which must be appended to the original document
+func (this *Stamper) WriteUpdate(w io.Writer) error {
+	return this.update.WriteUpdate(w)
+}
+
+// Add the content of a stamp to the selected pages
+func (this *Stamper) stamp(opts *StampOptions, contentOf func(pageno int) (*stampContent, error)) error {
+	if opts == nil {
+		opts = &StampOptions{}
+	}
+
+	ranges := opts.Pages
+	if ranges == nil {
+		ranges = []PageRange{{From: 1}}
+	}
+	pagenos, err := pageRangeNumbers(ranges, this.reader.numPages())
+	if err != nil {
+		return err
+	}
+
+	for _, pageno := range pagenos {
+		content, err := contentOf(pageno)
+		if err != nil {
+			return pageError(pageno, err)
+		}
+		if err := this.stampPage(pageno, content, opts); err != nil {
+			return pageError(pageno, err)
+		}
+	}
+
+	return nil
+}
+
+// Add the content of a stamp to a page: a content stream before or after the page content, and the resources it
+// uses, under names the page does not use yet
+func (this *Stamper) stampPage(pageno int, stamp *stampContent, opts *StampOptions) error {
+	node, err := this.reader.getPageNode(pageno)
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve page object")
+	}
+
+	boxes, err := this.reader.PageBoxes(pageno)
+	if err != nil {
+		return err
+	}
+
+	obj, err := this.update.EditObject(node.page.Id, node.page.Gen)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read page object")
+	}
+	page, ok := obj.AsDict()
+	if !ok {
+		return errors.New("Page object is not a dictionary")
+	}
+
+	// Resources of the page, copied as they may be shared with other pages
+	resourcesSpec, ok := page["/Resources"]
+	if !ok {
+		resourcesSpec, _ = node.attribute("/Resources")
+	}
+	resources := make(map[string]*PdfValue, 0)
+	if resourcesSpec != nil {
+		existing, err := this.reader.Resolve(resourcesSpec)
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve page resources")
+		}
+		if dict, ok := existing.AsDict(); ok {
+			for k, v := range dict {
+				resources[k] = v
+			}
+		}
+	}
+
+	// Add the resources of the stamp, renaming them where the page uses their names
+	content := stamp.content
+	for category, entries := range stamp.resources {
+		dict, err := this.resourceCategory(resources, category)
+		if err != nil {
+			return err
+		}
+		for name, ref := range entries {
+			unique := name
+			for i := 1; dict[unique] != nil; i++ {
+				unique = fmt.Sprintf("%s%d", name, i)
+			}
+			dict[unique] = ref
+			if unique != name {
+				content = strings.Replace(content, name+" ", unique+" ", -1)
+			}
+		}
+	}
+
+	var drawing strings.Builder
+	drawing.WriteString("q\n")
+
+	if opts.Opacity > 0 && opts.Opacity < 1 {
+		dict, err := this.resourceCategory(resources, "/ExtGState")
+		if err != nil {
+			return err
+		}
+		name := "/GOFPDISTAMPGS"
+		for i := 1; dict[name] != nil; i++ {
+			name = fmt.Sprintf("/GOFPDISTAMPGS%d", i)
+		}
+		dict[name] = this.opacityState(opts.Opacity)
+		drawing.WriteString(name + " gs\n")
+	}
+
+	m := stampMatrix(boxes, stamp.width, stamp.height, opts)
+	drawing.WriteString(fmt.Sprintf("%s %s %s %s %s %s cm\n", formatNumber(m[0]), formatNumber(m[1]), formatNumber(m[2]),
+		formatNumber(m[3]), formatNumber(m[4]), formatNumber(m[5])))
+	drawing.WriteString(content)
+	drawing.WriteString("Q\n")
+
+	// Existing content streams
+	contents := make([]*PdfValue, 0)
+	if contentsSpec := page["/Contents"]; contentsSpec != nil {
+		existing, err := this.reader.Resolve(contentsSpec)
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve page content")
+		}
+		if array, ok := existing.AsArray(); ok {
+			contents = append(contents, array...)
+		} else if contentsSpec.IsRef() {
+			contents = append(contents, contentsSpec)
+		}
+	}
+
+	if opts.Underlay {
+		ref, err := this.addStream([]byte(drawing.String()), nil)
+		if err != nil {
+			return err
+		}
+		contents = append([]*PdfValue{ref}, contents...)
+	} else {
+		// The page content may leave the graphics state changed, so it is saved before and restored after it
+		if this.save == nil {
+			if this.save, err = this.addStream([]byte("q\n"), nil); err != nil {
+				return err
+			}
+		}
+		ref, err := this.addStream([]byte("\nQ\n"+drawing.String()), nil)
+		if err != nil {
+			return err
+		}
+		contents = append(append([]*PdfValue{this.save}, contents...), ref)
+	}
+
+	page["/Contents"] = &PdfValue{Type: PDF_TYPE_ARRAY, Array: contents}
+	page["/Resources"] = &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: resources}
+
+	return nil
+}
+
+// Get a copy of a category of resources (e.g. /Font), which replaces the original in resources
+func (this *Stamper) resourceCategory(resources map[string]*PdfValue, category string) (map[string]*PdfValue, error) {
+	dict := make(map[string]*PdfValue, 0)
+
+	if spec := resources[category]; spec != nil {
+		existing, err := this.reader.Resolve(spec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve "+category+" resources")
+		}
+		if entries, ok := existing.AsDict(); ok {
+			for k, v := range entries {
+				dict[k] = v
+			}
+		}
+	}
+
+	resources[category] = &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: dict}
+
+	return dict, nil
+}
+
+// Compute the matrix that maps a stamp of the given size onto a page.  The stamp is placed on the crop box of the
+// page as it is displayed, then mapped back to the user space of the page, undoing its rotation and user unit.
+func stampMatrix(boxes *PageBoxes, width, height float64, opts *StampOptions) [6]float64 {
+	crop := boxes.CropBox
+	u := boxes.UserUnit
+
+	// Size of the displayed page in points
+	pageWidth, pageHeight := crop.Width()*u, crop.Height()*u
+	if boxes.Rotate == 90 || boxes.Rotate == 270 {
+		pageWidth, pageHeight = pageHeight, pageWidth
+	}
+
+	// Center of the stamp on the displayed page
+	cx, cy := pageWidth/2, pageHeight/2
+	switch opts.Position {
+	case StampTopLeft, StampBottomLeft:
+		cx = opts.Margin + width/2
+	case StampTopRight, StampBottomRight:
+		cx = pageWidth - opts.Margin - width/2
+	}
+	switch opts.Position {
+	case StampTopLeft, StampTop, StampTopRight:
+		cy = pageHeight - opts.Margin - height/2
+	case StampBottomLeft, StampBottom, StampBottomRight:
+		cy = opts.Margin + height/2
+	}
+	cx += opts.X
+	cy += opts.Y
+
+	// Rotate the stamp around its center
+	angle := opts.Rotation * math.Pi / 180
+	c, s := math.Cos(angle), math.Sin(angle)
+	m := [6]float64{c, s, -s, c, cx - c*width/2 + s*height/2, cy - s*width/2 - c*height/2}
+
+	// From points on the displayed page to the user space of the page
+	var display [6]float64
+	w, h := crop.Width(), crop.Height()
+	switch boxes.Rotate {
+	case 90:
+		display = [6]float64{0, 1, -1, 0, crop.Llx + w, crop.Lly}
+	case 180:
+		display = [6]float64{-1, 0, 0, -1, crop.Llx + w, crop.Lly + h}
+	case 270:
+		display = [6]float64{0, -1, 1, 0, crop.Llx, crop.Lly + h}
+	default:
+		display = [6]float64{1, 0, 0, 1, crop.Llx, crop.Lly}
+	}
+	for i := 0; i < 4; i++ {
+		display[i] /= u
+	}
+
+	return multiplyMatrix(m, display)
+}
+
+// Multiply two transformation matrices: the result transforms like a, then b
+func multiplyMatrix(a, b [6]float64) [6]float64 {
+	return [6]float64{
+		a[0]*b[0] + a[1]*b[2],
+		a[0]*b[1] + a[1]*b[3],
+		a[2]*b[0] + a[3]*b[2],
+		a[2]*b[1] + a[3]*b[3],
+		a[4]*b[0] + a[5]*b[2] + b[4],
+		a[4]*b[1] + a[5]*b[3] + b[5],
+	}
+}
+
+// Get a graphics state dictionary that sets the opacity of strokes and fills
+func (this *Stamper) opacityState(opacity float64) *PdfValue {
+	if ref, ok := this.opacity[opacity]; ok {
+		return ref
+	}
+
+	ref := this.update.AddObject(&PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+		"/Type": {Type: PDF_TYPE_TOKEN, Token: "/ExtGState"},
+		"/CA":   {Type: PDF_TYPE_REAL, Real: opacity},
+		"/ca":   {Type: PDF_TYPE_REAL, Real: opacity},
+	}})
+	this.opacity[opacity] = ref
+
+	return ref
+}
+
+// Get a reference to a standard font with WinAnsiEncoding, adding it to the update the first time
+func (this *Stamper) standardFontRef(base string) *PdfValue {
+	if ref, ok := this.fonts[base]; ok {
+		return ref
+	}
+
+	ref := this.update.AddObject(&PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: map[string]*PdfValue{
+		"/Type":     {Type: PDF_TYPE_TOKEN, Token: "/Font"},
+		"/Subtype":  {Type: PDF_TYPE_TOKEN, Token: "/Type1"},
+		"/BaseFont": {Type: PDF_TYPE_TOKEN, Token: base},
+		"/Encoding": {Type: PDF_TYPE_TOKEN, Token: "/WinAnsiEncoding"},
+	}})
+	this.fonts[base] = ref
+
+	return ref
+}
+
+// Add an image XObject and return a reference to it, along with its size in pixels.  JPEG images are embedded as
+// they are, other images are decoded and compressed, with their alpha channel as a soft mask.
+func (this *Stamper) addImage(data []byte) (*PdfValue, int, int, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, errors.Wrap(err, "Failed to read image")
+	}
+
+	dict := map[string]*PdfValue{
+		"/Type":             {Type: PDF_TYPE_TOKEN, Token: "/XObject"},
+		"/Subtype":          {Type: PDF_TYPE_TOKEN, Token: "/Image"},
+		"/Width":            {Type: PDF_TYPE_NUMERIC, Int: config.Width},
+		"/Height":           {Type: PDF_TYPE_NUMERIC, Int: config.Height},
+		"/BitsPerComponent": {Type: PDF_TYPE_NUMERIC, Int: 8},
+	}
+
+	if format == "jpeg" && (config.ColorModel == color.YCbCrModel || config.ColorModel == color.GrayModel) {
+		colorSpace := "/DeviceRGB"
+		if config.ColorModel == color.GrayModel {
+			colorSpace = "/DeviceGray"
+		}
+		dict["/ColorSpace"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: colorSpace}
+		dict["/Filter"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: "/DCTDecode"}
+
+		return this.update.AddObject(&PdfValue{Type: PDF_TYPE_STREAM, Value: &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: dict},
+			Stream: &PdfValue{Bytes: data}}), config.Width, config.Height, nil
+	}
+
+	var img image.Image
+	if format == "jpeg" {
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, 0, 0, errors.Wrap(err, "Failed to decode image")
+	}
+
+	bounds := img.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			if c.A != 0xff {
+				opaque = false
+			}
+		}
+	}
+
+	if !opaque {
+		mask, err := this.addStream(alpha, map[string]*PdfValue{
+			"/Type":             {Type: PDF_TYPE_TOKEN, Token: "/XObject"},
+			"/Subtype":          {Type: PDF_TYPE_TOKEN, Token: "/Image"},
+			"/Width":            {Type: PDF_TYPE_NUMERIC, Int: bounds.Dx()},
+			"/Height":           {Type: PDF_TYPE_NUMERIC, Int: bounds.Dy()},
+			"/BitsPerComponent": {Type: PDF_TYPE_NUMERIC, Int: 8},
+			"/ColorSpace":       {Type: PDF_TYPE_TOKEN, Token: "/DeviceGray"},
+		})
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		dict["/SMask"] = mask
+	}
+
+	dict["/Width"] = &PdfValue{Type: PDF_TYPE_NUMERIC, Int: bounds.Dx()}
+	dict["/Height"] = &PdfValue{Type: PDF_TYPE_NUMERIC, Int: bounds.Dy()}
+	dict["/ColorSpace"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: "/DeviceRGB"}
+
+	ref, err := this.addStream(rgb, dict)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return ref, bounds.Dx(), bounds.Dy(), nil
+}
+
+// Add a Flate compressed stream with the given dictionary entries and return a reference to it
+func (this *Stamper) addStream(data []byte, entries map[string]*PdfValue) (*PdfValue, error) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress stream")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "Failed to compress stream")
+	}
+
+	dict := make(map[string]*PdfValue, len(entries)+1)
+	for k, v := range entries {
+		dict[k] = v
+	}
+	dict["/Filter"] = &PdfValue{Type: PDF_TYPE_TOKEN, Token: "/FlateDecode"}
+
+	return this.update.AddObject(&PdfValue{Type: PDF_TYPE_STREAM, Value: &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: dict},
+		Stream: &PdfValue{Bytes: b.Bytes()}}), nil
+}
@@ -0,0 +1,68 @@
+package gofpdi
+
+import (
+	"math"
+	"testing"
+)
+
+// Map a point in the user space of a page to the page as it is displayed, in points
+func displayedPoint(boxes *PageBoxes, x, y float64) (float64, float64) {
+	crop := boxes.CropBox
+	switch boxes.Rotate {
+	case 90:
+		x, y = y-crop.Lly, crop.Urx-x
+	case 180:
+		x, y = crop.Urx-x, crop.Ury-y
+	case 270:
+		x, y = crop.Ury-y, x-crop.Llx
+	default:
+		x, y = x-crop.Llx, y-crop.Lly
+	}
+	return x * boxes.UserUnit, y * boxes.UserUnit
+}
+
+func TestStampMatrix(t *testing.T) {
+	crop := PageBox{Llx: 10, Lly: 20, Urx: 210, Ury: 120}
+
+	for _, rotate := range []int{0, 90, 180, 270} {
+		for _, userUnit := range []float64{1, 2} {
+			boxes := &PageBoxes{MediaBox: crop, CropBox: crop, UserUnit: userUnit, Rotate: rotate}
+
+			// Size of the displayed page in points
+			width, height := crop.Width()*userUnit, crop.Height()*userUnit
+			if rotate == 90 || rotate == 270 {
+				width, height = height, width
+			}
+
+			tests := []struct {
+				name     string
+				opts     StampOptions
+				llx, lly float64 // expected lower left corner of the stamp on the displayed page
+				dx, dy   float64 // expected direction of its baseline
+			}{
+				{"bottom left", StampOptions{Position: StampBottomLeft}, 0, 0, 1, 0},
+				{"top right", StampOptions{Position: StampTopRight, Margin: 5}, width - 25, height - 15, 1, 0},
+				{"center", StampOptions{Position: StampCenter, X: 3, Y: -4}, width/2 - 7, height/2 - 9, 1, 0},
+				// Turned counterclockwise around its center
+				{"rotated", StampOptions{Position: StampCenter, Rotation: 90}, width/2 + 5, height/2 - 10, 0, 1},
+			}
+
+			for _, test := range tests {
+				m := stampMatrix(boxes, 20, 10, &test.opts)
+
+				// Lower left corner and the end of the baseline of the 20 x 10 stamp
+				x0, y0 := displayedPoint(boxes, m[4], m[5])
+				x1, y1 := displayedPoint(boxes, 20*m[0]+m[4], 20*m[1]+m[5])
+
+				if math.Abs(x0-test.llx) > 1e-9 || math.Abs(y0-test.lly) > 1e-9 {
+					t.Errorf("/Rotate %d, /UserUnit %v, %s: stamp at %v, %v, want %v, %v", rotate, userUnit, test.name,
+						x0, y0, test.llx, test.lly)
+				}
+				if math.Abs(x1-x0-20*test.dx) > 1e-9 || math.Abs(y1-y0-20*test.dy) > 1e-9 {
+					t.Errorf("/Rotate %d, /UserUnit %v, %s: baseline %v, %v, want %v, %v", rotate, userUnit, test.name,
+						x1-x0, y1-y0, 20*test.dx, 20*test.dy)
+				}
+			}
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package text
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMissingFont is returned when text is shown with a font that is not defined in the page resources
+var ErrMissingFont = errors.New("missing font")
 
 type FontDefinition struct {
 	Name       string
@@ -20,6 +26,10 @@ func (f *FontDefinition) CalculateGlyphWidth(char uint8, tjAdjustment float64, f
 		calcErr = fmt.Errorf("char %d is outside range of allowed values for font %s", char, f.Name)
 		return
 	}
+	if int(char-f.FirstChar) >= len(f.Widths) {
+		calcErr = fmt.Errorf("char %d has no width in font %s", char, f.Name)
+		return
+	}
 	width = (float64(f.Widths[char-f.FirstChar]) - tjAdjustment/1000) * fontSize
 	if char == 32 {
 		width += wordSpacing
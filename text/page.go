@@ -57,7 +57,7 @@ func (r *PageRender) addLineItem(chars []*ShowChars) error {
 
 	font, found := r.Fonts[r.FontName]
 	if !found {
-		return fmt.Errorf("font '%s' not found in available page fonts", r.FontName)
+		return errors.Wrapf(ErrMissingFont, "font '%s' not found in available page fonts", r.FontName)
 	}
 
 	line := ShowOperation{
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"sort"
 
 	"github.com/pkg/errors"
 )
@@ -33,6 +34,11 @@ type PdfWriter struct {
 	current_obj_id  int
 	tpl_id_offset   int
 	use_hash        bool
+	// Write indirect references with their original object ids instead of importing the objects
+	keep_obj_ids bool
+	// Pages and their widgets by object id, with the id of their page; they are written as null instead of being
+	// imported unless the page is copied
+	page_obj_ids map[int]int
 }
 
 type PdfObjectId struct {
@@ -96,6 +102,7 @@ type PdfTemplate struct {
 	W         float64
 	H         float64
 	Rotation  int
+	UserUnit  float64 // size of a user space unit of the page in points
 	N         int
 }
 
@@ -114,31 +121,32 @@ func (this *PdfWriter) ClearImportedObjects() {
 }
 
 // Create a PdfTemplate object from a page number (e.g. 1) and a boxName (e.g. MediaBox)
-func (this *PdfWriter) ImportPage(reader *PdfReader, pageno int, boxName string) (int, error) {
+func (this *PdfWriter) ImportPage(reader *PdfReader, pageno int, boxName string, opts ...ImportOption) (int, error) {
 	var err error
 
+	options := &importOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	// Set default scale to 1
 	this.k = 1
 
-	// Get all page boxes
-	pageBoxes, err := reader.getPageBoxes(1, this.k)
+	// Get the effective page boxes.  Boxes the page does not define fall back to the crop box (bleed, trim and art
+	// box) or the media box (crop box), so every available box name is present.
+	pageBoxes, err := reader.getPageBoxes(pageno, this.k)
 	if err != nil {
 		return -1, errors.Wrap(err, "Failed to get page boxes")
 	}
 
-	// If requested box name does not exist for this page, use an alternate box
+	// If the requested box name or an alternate box name cannot be found, trigger an error
 	if _, ok := pageBoxes[boxName]; !ok {
-		if boxName == "/BleedBox" || boxName == "/TrimBox" || boxName == "ArtBox" {
-			boxName = "/CropBox"
-		} else if boxName == "/CropBox" {
-			boxName = "/MediaBox"
-		}
+		return -1, &PageError{Page: pageno, Err: errors.Wrap(ErrBoxNotFound, boxName)}
 	}
 
-	// If the requested box name or an alternate box name cannot be found, trigger an error
-	// TODO: Improve error handling
-	if _, ok := pageBoxes[boxName]; !ok {
-		return -1, errors.New("Box not found: " + boxName)
+	userUnit := 1.0
+	if boxes, err := reader.PageBoxes(pageno); err == nil {
+		userUnit = boxes.UserUnit
 	}
 
 	pageResources, err := reader.getPageResources(pageno)
@@ -151,6 +159,13 @@ func (this *PdfWriter) ImportPage(reader *PdfReader, pageno int, boxName string)
 		return -1, errors.Wrap(err, "Failed to get content")
 	}
 
+	if options.annotations {
+		content, pageResources, err = reader.addAnnotationAppearances(pageno, content, pageResources)
+		if err != nil {
+			return -1, err
+		}
+	}
+
 	// Set template values
 	tpl := &PdfTemplate{}
 	tpl.Reader = reader
@@ -158,6 +173,7 @@ func (this *PdfWriter) ImportPage(reader *PdfReader, pageno int, boxName string)
 	tpl.Buffer = content
 	tpl.Box = pageBoxes[boxName]
 	tpl.Boxes = pageBoxes
+	tpl.UserUnit = userUnit
 	tpl.X = 0
 	tpl.Y = 0
 	tpl.W = tpl.Box["w"]
@@ -291,16 +307,19 @@ func (this *PdfWriter) writeValue(value *PdfValue) {
 		break
 
 	case PDF_TYPE_OBJREF:
-		// An indirect object reference.  Fill the object stack if needed.
-		// Check to see if object already exists on the don_obj_stack.
-		if _, ok := this.don_obj_stack[value.Id]; !ok {
-			this.newObj(-1, true)
-			this.obj_stack[value.Id] = &PdfValue{Type: PDF_TYPE_OBJREF, Gen: value.Gen, Id: value.Id, NewId: this.n}
-			this.don_obj_stack[value.Id] = &PdfValue{Type: PDF_TYPE_OBJREF, Gen: value.Gen, Id: value.Id, NewId: this.n}
+		if this.keep_obj_ids {
+			this.straightOut(fmt.Sprintf("%d %d R ", value.Id, value.Gen))
+			break
+		}
+		if pageId, ok := this.page_obj_ids[value.Id]; ok {
+			if _, copied := this.don_obj_stack[pageId]; !copied {
+				this.straightOut("null ")
+				break
+			}
 		}
 
-		// Get object ID from don_obj_stack
-		objId := this.don_obj_stack[value.Id].NewId
+		// An indirect object reference.  Fill the object stack if needed.
+		objId := this.importObjRef(value)
 		this.outObjRef(objId)
 		//this.out(fmt.Sprintf("%d 0 R", objId))
 		break
@@ -312,7 +331,13 @@ func (this *PdfWriter) writeValue(value *PdfValue) {
 
 	case PDF_TYPE_STREAM:
 		// A stream.  First, output the stream dictionary, then the stream data itself.
-		this.writeValue(value.Value)
+		// The /Length is taken from the stream data, which may differ from the source (e.g. after decryption).
+		dict := &PdfValue{Type: PDF_TYPE_DICTIONARY, Dictionary: make(map[string]*PdfValue, len(value.Value.Dictionary))}
+		for k, v := range value.Value.Dictionary {
+			dict.Dictionary[k] = v
+		}
+		dict.Dictionary["/Length"] = &PdfValue{Type: PDF_TYPE_NUMERIC, Int: len(value.Stream.Bytes)}
+		this.writeValue(dict)
 		this.out("stream")
 		this.out(string(value.Stream.Bytes))
 		this.out("endstream")
@@ -388,45 +413,8 @@ func (this *PdfWriter) PutFormXobjects(reader *PdfReader) (map[string]*PdfObject
 
 		this.out(fmt.Sprintf("/BBox [%.2F %.2F %.2F %.2F]", tpl.Box["llx"]*this.k, tpl.Box["lly"]*this.k, (tpl.Box["urx"]+tpl.X)*this.k, (tpl.Box["ury"]-tpl.Y)*this.k))
 
-		var c, s, tx, ty float64
-		c = 1
-
-		// Handle rotated pages
-		if tpl.Box != nil {
-			tx = -tpl.Box["llx"]
-			ty = -tpl.Box["lly"]
-
-			if tpl.Rotation != 0 {
-				angle := float64(tpl.Rotation) * math.Pi / 180.0
-				c = math.Cos(float64(angle))
-				s = math.Sin(float64(angle))
-
-				switch tpl.Rotation {
-				case -90:
-					tx = -tpl.Box["lly"]
-					ty = tpl.Box["urx"]
-					break
-
-				case -180:
-					tx = tpl.Box["urx"]
-					ty = tpl.Box["ury"]
-					break
-
-				case -270:
-					tx = tpl.Box["ury"]
-					ty = -tpl.Box["llx"]
-				}
-			}
-		} else {
-			tx = -tpl.Box["x"] * 2
-			ty = tpl.Box["y"] * 2
-		}
-
-		tx *= this.k
-		ty *= this.k
-
-		if c != 1 || s != 0 || tx != 0 || ty != 0 {
-			this.out(fmt.Sprintf("/Matrix [%.5F %.5F %.5F %.5F %.5F %.5F]", c, s, -s, c, tx, ty))
+		if matrix := this.templateMatrix(tpl); matrix != [6]float64{1, 0, 0, 1, 0, 0} {
+			this.out(fmt.Sprintf("/Matrix [%.5F %.5F %.5F %.5F %.5F %.5F]", matrix[0], matrix[1], matrix[2], matrix[3], matrix[4], matrix[5]))
 		}
 
 		// Now write resources
@@ -462,26 +450,91 @@ func (this *PdfWriter) PutFormXobjects(reader *PdfReader) (map[string]*PdfObject
 	return result, nil
 }
 
+// Get the matrix of the Form XObject of a template, which moves the lower left corner of its box to the origin,
+// turns rotated pages upright and scales pages with a /UserUnit other than 1 to their size in points
+func (this *PdfWriter) templateMatrix(tpl *PdfTemplate) [6]float64 {
+	var c, s, tx, ty float64
+	c = 1
+
+	// Handle rotated pages
+	if tpl.Box != nil {
+		tx = -tpl.Box["llx"]
+		ty = -tpl.Box["lly"]
+
+		if tpl.Rotation != 0 {
+			angle := float64(tpl.Rotation) * math.Pi / 180.0
+			c = math.Cos(float64(angle))
+			s = math.Sin(float64(angle))
+
+			switch tpl.Rotation {
+			case -90:
+				tx = -tpl.Box["lly"]
+				ty = tpl.Box["urx"]
+				break
+
+			case -180:
+				tx = tpl.Box["urx"]
+				ty = tpl.Box["ury"]
+				break
+
+			case -270:
+				tx = tpl.Box["ury"]
+				ty = -tpl.Box["llx"]
+			}
+		}
+	} else {
+		tx = -tpl.Box["x"] * 2
+		ty = tpl.Box["y"] * 2
+	}
+
+	tx *= this.k
+	ty *= this.k
+
+	// Scale pages with a /UserUnit other than 1 to their size in points
+	u := tpl.UserUnit
+	if u <= 0 {
+		u = 1
+	}
+
+	return [6]float64{c * u, s * u, -s * u, c * u, tx * u, ty * u}
+}
+
+// Get the new id of an object of the source document, adding the object to the object stack if it has not been
+// written yet
+func (this *PdfWriter) importObjRef(value *PdfValue) int {
+	// Check to see if object already exists on the don_obj_stack.
+	if _, ok := this.don_obj_stack[value.Id]; !ok {
+		this.newObj(-1, true)
+		this.obj_stack[value.Id] = &PdfValue{Type: PDF_TYPE_OBJREF, Gen: value.Gen, Id: value.Id, NewId: this.n}
+		this.don_obj_stack[value.Id] = &PdfValue{Type: PDF_TYPE_OBJREF, Gen: value.Gen, Id: value.Id, NewId: this.n}
+	}
+
+	// Get object ID from don_obj_stack
+	return this.don_obj_stack[value.Id].NewId
+}
+
 func (this *PdfWriter) putImportedObjects(reader *PdfReader) error {
 	var err error
 	var nObj *PdfValue
 
-	// obj_stack will have new items added to it in the inner loop, so do another loop to check for extras
-	// TODO make the order of this the same every time
-	for {
-		atLeastOne := false
+	// obj_stack will have new items added to it while writing objects, so repeat until it is empty
+	for len(this.obj_stack) > 0 {
+		ids := make([]int, 0, len(this.obj_stack))
+		for id := range this.obj_stack {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		for _, k := range ids {
+			v := this.obj_stack[k]
 
-		// FIXME:  How to determine number of objects before this loop?
-		for i := 0; i < 9999; i++ {
-			k := i
-			v := this.obj_stack[i]
+			// Remove from stack
+			delete(this.obj_stack, k)
 
 			if v == nil {
 				continue
 			}
 
-			atLeastOne = true
-
 			nObj, err = reader.resolveObject(v)
 			if err != nil {
 				return errors.Wrap(err, "Unable to resolve object")
@@ -497,13 +550,6 @@ func (this *PdfWriter) putImportedObjects(reader *PdfReader) error {
 			}
 
 			this.endObj()
-
-			// Remove from stack
-			this.obj_stack[k] = nil
-		}
-
-		if !atLeastOne {
-			break
 		}
 	}
 
@@ -548,7 +594,7 @@ func (this *PdfWriter) UseTemplate(tplid int, _x float64, _y float64, _w float64
 	_x += tpl.X
 	_y += tpl.Y
 
-	wh := this.getTemplateSize(0, _w, _h)
+	wh := this.getTemplateSize(tplid, _w, _h)
 
 	_w = wh["w"]
 	_h = wh["h"]